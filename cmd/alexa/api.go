@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newAPICmd(flags *rootFlags) *cobra.Command {
+	var host string
+	var data string
+
+	cmd := &cobra.Command{
+		Use:   "api <method> <path>",
+		Short: "Make an authenticated request to any Alexa endpoint",
+		Long: `Make a request to an Alexa endpoint the CLI doesn't wrap, using the
+CLI's session, and print the response.
+
+--host picks the server and the credentials sent:
+  layla   Alexa API (pitangui/layla), cookies and CSRF (default)
+  alexa   alexa.<domain>, cookies and CSRF
+  avs     AVS, bearer token
+  www     www.<domain>, cookies and the activity CSRF token
+
+--data sends a JSON body: a literal, @file, or @- for stdin. JSON
+responses are pretty-printed; with --json they are wrapped in the usual
+envelope.
+
+Examples:
+  alexacli api GET /api/devices-v2/device
+  alexacli api GET '/api/notifications'
+  alexacli api GET /v1/conversations --host avs
+  alexacli api PUT /api/phoenix/state --data @request.json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			h, err := api.ParseHost(host)
+			if err != nil {
+				return err
+			}
+
+			var body []byte
+			if cmd.Flags().Changed("data") {
+				if body, err = readData(data); err != nil {
+					return err
+				}
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			resp, err := client.Raw(ctx, h, args[0], args[1], body)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				if json.Valid(resp) {
+					return out.Data(json.RawMessage(resp))
+				}
+				return out.Data(string(resp))
+			}
+
+			if len(resp) == 0 {
+				return nil
+			}
+			fmt.Println(strings.TrimRight(indentJSON(resp), "\n"))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&host, "host", string(api.HostAPI), "Server to send the request to: layla, alexa, avs or www")
+	cmd.Flags().StringVar(&data, "data", "", "Request body: JSON, @file, or @- for stdin")
+
+	return cmd
+}
+
+// readData returns a request body given as a literal, @file or @- for stdin
+func readData(data string) ([]byte, error) {
+	path, ok := strings.CutPrefix(data, "@")
+	if !ok {
+		return []byte(data), nil
+	}
+
+	b, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return b, nil
+}
@@ -30,9 +30,10 @@ Examples:
   alexacli ask "what time is it" -d Bedroom`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClientWithFlags(flags)
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
@@ -41,7 +42,7 @@ Examples:
 				return fmt.Errorf("device is required (use -d)")
 			}
 
-			dev, err := findDevice(client, device)
+			dev, err := findDevice(ctx, client, device)
 			if err != nil {
 				return err
 			}
@@ -49,7 +50,7 @@ Examples:
 			question := strings.Join(args, " ")
 			timeoutDuration := time.Duration(timeout) * time.Second
 
-			response, err := client.Ask(dev, question, timeoutDuration)
+			response, err := client.Ask(ctx, dev, question, timeoutDuration)
 			if err != nil {
 				return err
 			}
@@ -12,7 +12,6 @@ func newAskPlusCmd(flags *rootFlags) *cobra.Command {
 	var timeout int
 	var conversationID string
 	var device string
-	var verbose bool
 
 	cmd := &cobra.Command{
 		Use:   "askplus <question>",
@@ -42,45 +41,37 @@ Examples:
   alexacli askplus -d Kitchen -t 30 "Explain quantum computing"`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			// Resolve conversation ID from device name if provided
 			if device != "" && conversationID == "" {
-				convID, err := client.GetConversationForDevice(device)
+				convID, err := client.GetConversationForDevice(ctx, device)
 				if err != nil {
 					return err
 				}
 				conversationID = convID
 			}
 
-			// Set conversation ID if provided
-			if conversationID != "" {
-				client.SetConversationID(conversationID)
-			}
-
-			// Enable verbose mode
-			if verbose {
-				client.SetVerbose(true)
-			}
-
 			question := strings.Join(args, " ")
 			timeoutDuration := time.Duration(timeout) * time.Second
 
-			response, err := client.AskPlus(question, timeoutDuration)
+			conversationID, response, err := client.AskPlus(ctx, conversationID, question, timeoutDuration)
 			if err != nil {
 				return err
 			}
 
 			if flags.asJSON {
 				return out.Data(map[string]string{
-					"question": question,
-					"response": response,
-					"type":     "alexa_plus",
+					"question":       question,
+					"response":       response,
+					"conversationId": conversationID,
+					"type":           "alexa_plus",
 				})
 			}
 
@@ -92,7 +83,6 @@ Examples:
 	cmd.Flags().IntVarP(&timeout, "timeout", "t", 15, "Timeout in seconds to wait for response")
 	cmd.Flags().StringVarP(&conversationID, "conversation", "c", "", "Conversation ID (use -d for easier device-based selection)")
 	cmd.Flags().StringVarP(&device, "device", "d", "", "Device name (auto-selects most recent conversation)")
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show debug output")
 
 	return cmd
 }
@@ -28,6 +28,7 @@ the refresh token to paste here.
 Alternatively, set the ALEXA_REFRESH_TOKEN environment variable.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
 			var token string
@@ -48,23 +49,31 @@ Alternatively, set the ALEXA_REFRESH_TOKEN environment variable.`,
 				return fmt.Errorf("refresh token is required")
 			}
 
-			// Validate the token by trying to authenticate
-			client, err := api.NewClient(token, domain)
+			// Keep settings such as device groups and endpoints from any existing file
+			cfg, err := config.ReadFile()
+			if err != nil {
+				cfg = &config.Config{}
+			}
+
+			// Validate the token against the same endpoints later commands will use
+			endpoints := cfg.Endpoints
+			config.ApplyEnv(&endpoints)
+			client, err := api.NewClientWithOptions(ctx, token, domain, api.Options{
+				Endpoints: endpoints,
+			})
 			if err != nil {
 				return fmt.Errorf("authentication failed: %w", err)
 			}
 
 			// Try to get devices to verify it works
-			devices, err := client.GetDevices()
+			devices, err := client.GetDevices(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to verify token: %w", err)
 			}
 
-			// Save configuration
-			cfg := &config.Config{
-				RefreshToken: token,
-				AmazonDomain: domain,
-			}
+			// Save configuration. Environment overrides stay out of the file.
+			cfg.RefreshToken = token
+			cfg.AmazonDomain = domain
 
 			if err := config.Save(cfg); err != nil {
 				return fmt.Errorf("failed to save config: %w", err)
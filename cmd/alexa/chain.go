@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newChainCmd(flags *rootFlags) *cobra.Command {
+	var device string
+	var file string
+	var parallel bool
+
+	cmd := &cobra.Command{
+		Use:   "chain <step>...",
+		Short: "Run several steps on a device as one sequence",
+		Long: `Run several steps on an Alexa device as a single sequence.
+
+All steps are sent to Alexa in one request, so they run in order (or
+together with --parallel) without racing each other.
+
+Steps are written as <kind>:<argument>:
+  speak:<text>          Speak text
+  announcement:<text>   Announce text
+  textcommand:<text>    Run a voice command, as if spoken
+  volume:<0-100>        Set the volume
+  wait:<duration>       Pause, e.g. wait:5s (serial chains only)
+  sound:<id>            Play a sound (see 'alexacli sounds list')
+  push:<text>           Send a notification to the Alexa app
+
+The built-in skills weather, traffic, flashbriefing, goodmorning, joke,
+funfact, singasong and stop are steps on their own, with no argument.
+
+Steps can also be read from a file (or - for stdin) with -f, one per
+line. Blank lines and lines starting with # are ignored.
+
+Examples:
+  alexacli chain -d Kitchen 'volume:30' 'speak:Dinner is ready' 'wait:5s' 'textcommand:turn off the lights'
+  alexacli chain -d Office --parallel 'speak:Hello' 'textcommand:turn on the desk lamp'
+  alexacli chain -d Kitchen -f bedtime.txt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			steps := args
+			if file != "" {
+				fileSteps, err := readSteps(file)
+				if err != nil {
+					return err
+				}
+				steps = append(fileSteps, steps...)
+			}
+			if len(steps) == 0 {
+				return fmt.Errorf("no steps given (pass them as arguments or use -f)")
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			dev, err := findDevice(ctx, client, device)
+			if err != nil {
+				return err
+			}
+
+			nodes := make([]api.Node, len(steps))
+			for i, step := range steps {
+				if parallel && strings.HasPrefix(strings.ToLower(strings.TrimSpace(step)), "wait:") {
+					return fmt.Errorf("wait steps only make sense in a serial chain")
+				}
+				if nodes[i], err = client.ParseStep(dev, step); err != nil {
+					return err
+				}
+			}
+
+			seq := api.NewSequence(nodes...)
+			if parallel && len(nodes) > 1 {
+				seq = api.Sequence{Start: api.ParallelNode(nodes)}
+			}
+
+			if err := client.RunSequence(ctx, seq); err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(map[string]interface{}{
+					"device":   dev.AccountName,
+					"steps":    steps,
+					"parallel": parallel,
+				})
+			}
+			return out.Success(fmt.Sprintf("Ran %d step(s) on %s", len(steps), dev.AccountName))
+		},
+	}
+
+	cmd.Flags().StringVarP(&device, "device", "d", "", "Device name or serial (required)")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read steps from a file, one per line (- for stdin)")
+	cmd.Flags().BoolVar(&parallel, "parallel", false, "Run the steps at the same time instead of in order")
+	cmd.MarkFlagRequired("device")
+
+	return cmd
+}
+
+// readSteps reads chain steps from a file, skipping blank lines and # comments
+func readSteps(path string) ([]string, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read steps: %w", err)
+	}
+
+	var steps []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		steps = append(steps, line)
+	}
+	return steps, nil
+}
@@ -24,9 +24,10 @@ Examples:
   alexacli command "set a timer for 5 minutes" -d Kitchen`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClientWithFlags(flags)
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
@@ -35,14 +36,14 @@ Examples:
 				return fmt.Errorf("device is required (use -d)")
 			}
 
-			dev, err := findDevice(client, device)
+			dev, err := findDevice(ctx, client, device)
 			if err != nil {
 				return err
 			}
 
 			text := strings.Join(args, " ")
 
-			if err := client.SequenceCommand(dev, fmt.Sprintf("textcommand:'%s'", text)); err != nil {
+			if err := client.SequenceCommand(ctx, dev, fmt.Sprintf("textcommand:'%s'", text)); err != nil {
 				return err
 			}
 
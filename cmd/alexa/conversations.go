@@ -9,8 +9,6 @@ import (
 )
 
 func newConversationsCmd(flags *rootFlags) *cobra.Command {
-	var verbose bool
-
 	cmd := &cobra.Command{
 		Use:   "conversations",
 		Short: "List all Alexa+ conversations with device names",
@@ -36,18 +34,15 @@ Examples:
   # Or use a specific conversation ID
   alexacli askplus -c "amzn1.conversation.xxx" "Hello"`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
-			if verbose {
-				client.SetVerbose(true)
-			}
-
-			conversations, err := client.GetConversations()
+			conversations, err := client.GetConversations(ctx)
 			if err != nil {
 				return err
 			}
@@ -78,8 +73,6 @@ Examples:
 		},
 	}
 
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show debug output")
-
 	return cmd
 }
 
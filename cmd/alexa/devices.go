@@ -11,14 +11,15 @@ func newDevicesCmd(flags *rootFlags) *cobra.Command {
 		Short: "List Alexa devices",
 		Long:  `List all Alexa devices registered to your account.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClientWithFlags(flags)
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
-			devices, err := client.GetDevices()
+			devices, err := client.GetDevices(ctx)
 			if err != nil {
 				return err
 			}
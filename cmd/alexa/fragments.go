@@ -23,17 +23,17 @@ Examples:
   alexacli fragments amzn1.conversation.xxx --json`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			conversationID := args[0]
-			client.SetConversationID(conversationID)
 
-			resp, err := client.GetConversationFragments()
+			resp, err := client.GetConversationFragments(ctx, conversationID)
 			if err != nil {
 				return err
 			}
@@ -23,15 +23,16 @@ Examples:
   alexacli history --limit 5
   alexacli history --json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			// Get devices first to ensure we have customer ID
-			_, err = client.GetDevices()
+			_, err = client.GetDevices(ctx)
 			if err != nil {
 				return err
 			}
@@ -40,7 +41,7 @@ Examples:
 			endTime := time.Now().UnixMilli()
 			startTime := endTime - (24 * 60 * 60 * 1000) // 24 hours ago
 
-			records, err := client.GetCustomerHistoryRecords(startTime, endTime)
+			records, err := client.GetCustomerHistoryRecords(ctx, startTime, endTime)
 			if err != nil {
 				return err
 			}
@@ -1,13 +1,68 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+)
+
+// Process exit codes, so scripts can tell failure modes apart
+const (
+	exitError          = 1   // anything not listed below
+	exitAuthExpired    = 3   // refresh token rejected, re-run 'alexacli auth'
+	exitDeviceNotFound = 4   // no device matched -d
+	exitAmbiguousMatch = 5   // -d matched several devices
+	exitTimeout        = 6   // Alexa did not answer in time
+	exitRateLimited    = 7   // Amazon is throttling requests
+	exitAPIError       = 8   // any other error status from Amazon
+	exitDeviceOffline  = 9   // the targeted device is offline
+	exitInterrupted    = 130 // cancelled with Ctrl-C
 )
 
 func main() {
-	if err := execute(os.Args[1:]); err != nil {
+	// Cancel in-flight requests and polling on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := execute(ctx, os.Args[1:])
+	if errors.Is(err, api.ErrDryRun) {
+		// The request was printed instead of sent, which is what was asked for
+		err = nil
+	}
+	if err != nil {
+		stop()
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode maps an error to the process exit code for its failure mode
+func exitCode(err error) int {
+	var apiErr *api.APIError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, api.ErrAuthExpired):
+		return exitAuthExpired
+	case errors.Is(err, api.ErrDeviceNotFound):
+		return exitDeviceNotFound
+	case errors.Is(err, api.ErrDeviceOffline):
+		return exitDeviceOffline
+	case errors.Is(err, api.ErrAmbiguousMatch):
+		return exitAmbiguousMatch
+	case errors.Is(err, api.ErrTimeout):
+		return exitTimeout
+	case errors.Is(err, api.ErrRateLimited):
+		return exitRateLimited
+	case errors.As(err, &apiErr):
+		return exitAPIError
+	default:
+		return exitError
 	}
 }
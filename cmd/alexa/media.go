@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newMediaCmd(flags *rootFlags) *cobra.Command {
+	var device string
+
+	cmd := &cobra.Command{
+		Use:   "media",
+		Short: "Control media playback",
+		Long: `Control what an Alexa device is playing and show its status.
+
+Without -d, the default_device from the config file is used.
+
+Examples:
+  alexacli media status -d Kitchen
+  alexacli media pause -d Kitchen
+  alexacli media next -d Kitchen
+  alexacli media seek 1:30 -d Kitchen
+  alexacli media seek +30s -d Kitchen
+  alexacli media shuffle on -d Kitchen
+  alexacli media status -d Kitchen --json`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&device, "device", "d", "", "Device name or serial (default from config)")
+
+	cmd.AddCommand(newMediaStatusCmd(flags, &device))
+	cmd.AddCommand(newMediaTransportCmd(flags, &device, "play", "Resume playback", api.MediaPlay))
+	cmd.AddCommand(newMediaTransportCmd(flags, &device, "pause", "Pause playback", api.MediaPause))
+	cmd.AddCommand(newMediaTransportCmd(flags, &device, "next", "Skip to the next track", api.MediaNext))
+	cmd.AddCommand(newMediaTransportCmd(flags, &device, "previous", "Go back to the previous track", api.MediaPrevious))
+	cmd.AddCommand(newMediaSeekCmd(flags, &device))
+	cmd.AddCommand(newMediaToggleCmd(flags, &device, "shuffle", (*api.Client).SetShuffle))
+	cmd.AddCommand(newMediaToggleCmd(flags, &device, "repeat", (*api.Client).SetRepeat))
+
+	return cmd
+}
+
+func newMediaStatusCmd(flags *rootFlags, device *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show what is playing",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, dev, err := deviceClient(ctx, flags, *device)
+			if err != nil {
+				return err
+			}
+
+			np, err := client.GetNowPlaying(ctx, dev)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(struct {
+					Device string `json:"device"`
+					*api.NowPlaying
+					PositionSeconds int64 `json:"positionSeconds"`
+					LengthSeconds   int64 `json:"lengthSeconds"`
+				}{dev.AccountName, np, int64(np.Position.Seconds()), int64(np.Length.Seconds())})
+			}
+
+			fmt.Printf("%s: %s\n", dev.AccountName, np.State)
+			if np.Title == "" {
+				return nil
+			}
+
+			track := np.Title
+			if np.Artist != "" {
+				track += " - " + np.Artist
+			}
+			if np.Album != "" {
+				track += " (" + np.Album + ")"
+			}
+			fmt.Printf("  %s\n", track)
+
+			progress := formatPosition(np.Position)
+			if np.Length > 0 {
+				progress += " / " + formatPosition(np.Length)
+			}
+			if np.Provider != "" {
+				progress = np.Provider + "  " + progress
+			}
+			fmt.Printf("  %s\n", progress)
+
+			muted := ""
+			if np.Muted {
+				muted = " (muted)"
+			}
+			fmt.Printf("  Volume %d%s  Shuffle %s  Repeat %s\n", np.Volume, muted, onOff(np.Shuffle), onOff(np.Repeat))
+			return nil
+		},
+	}
+}
+
+func newMediaTransportCmd(flags *rootFlags, device *string, use, short string, command api.MediaCommand) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, dev, err := deviceClient(ctx, flags, *device)
+			if err != nil {
+				return err
+			}
+
+			if err := client.SendMediaCommand(ctx, dev, command); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Sent %s to %s", use, dev.AccountName))
+		},
+	}
+	if use == "previous" {
+		cmd.Aliases = []string{"prev"}
+	}
+	return cmd
+}
+
+func newMediaSeekCmd(flags *rootFlags, device *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "seek <position>",
+		Short: "Jump to a position in the current track",
+		Long: `Jump to a position in the current track.
+
+The position is seconds (90), minutes and seconds (1:30) or a duration
+(1m30s). Prefix it with + or - to move relative to the current position;
+put -- before a negative step so it isn't read as a flag.
+
+Examples:
+  alexacli media seek 1:30 -d Kitchen
+  alexacli media seek +30s -d Kitchen
+  alexacli media seek -d Kitchen -- -15`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			arg := args[0]
+			relative := strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-")
+			offset, err := parsePosition(strings.TrimLeft(arg, "+-"))
+			if err != nil {
+				return err
+			}
+
+			client, dev, err := deviceClient(ctx, flags, *device)
+			if err != nil {
+				return err
+			}
+
+			position := offset
+			if relative {
+				np, err := client.GetNowPlaying(ctx, dev)
+				if err != nil {
+					return err
+				}
+				if strings.HasPrefix(arg, "-") {
+					offset = -offset
+				}
+				position = max(np.Position+offset, 0)
+				if np.Length > 0 {
+					position = min(position, np.Length)
+				}
+			}
+
+			if err := client.SeekMedia(ctx, dev, position); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Moved %s to %s", dev.AccountName, formatPosition(position)))
+		},
+	}
+}
+
+func newMediaToggleCmd(flags *rootFlags, device *string, use string, set func(*api.Client, context.Context, *api.Device, bool) error) *cobra.Command {
+	return &cobra.Command{
+		Use:       use + " <on|off>",
+		Short:     "Turn " + use + " on or off",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"on", "off"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			var on bool
+			switch strings.ToLower(args[0]) {
+			case "on":
+				on = true
+			case "off":
+				on = false
+			default:
+				return fmt.Errorf("expected on or off, got %q", args[0])
+			}
+
+			client, dev, err := deviceClient(ctx, flags, *device)
+			if err != nil {
+				return err
+			}
+
+			if err := set(client, ctx, dev, on); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("%s %s for %s", strings.ToUpper(use[:1])+use[1:], onOff(on), dev.AccountName))
+		},
+	}
+}
+
+// parsePosition parses "90", "1:30" or "1m30s"
+func parsePosition(s string) (time.Duration, error) {
+	if mins, secs, ok := strings.Cut(s, ":"); ok {
+		m, err1 := strconv.Atoi(mins)
+		sec, err2 := strconv.Atoi(secs)
+		if err1 == nil && err2 == nil && m >= 0 && sec >= 0 && sec < 60 {
+			return time.Duration(m)*time.Minute + time.Duration(sec)*time.Second, nil
+		}
+	} else if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, nil
+	} else if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid position %q: use seconds, m:ss or a duration like 1m30s", s)
+}
+
+// formatPosition formats a track position as m:ss
+func formatPosition(d time.Duration) string {
+	secs := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
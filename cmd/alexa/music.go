@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newMusicCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "music",
+		Short: "Search for and play music",
+		Long:  `Search a music provider and play the result on a device or multi-room group.`,
+	}
+
+	cmd.AddCommand(newMusicPlayCmd(flags))
+
+	return cmd
+}
+
+func newMusicPlayCmd(flags *rootFlags) *cobra.Command {
+	var device string
+	var provider string
+	var wait time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "play <phrase>",
+		Short: "Search for music and play it",
+		Long: `Search a music provider for a phrase and play the result.
+
+-d can name an Echo or a multi-room music group (such as "Everywhere").
+Providers: amazon, spotify, tunein, apple, deezer, iheart, library. The
+provider must be linked to your Alexa account.
+
+After starting playback, the command waits up to --wait for the device to
+report what is playing. Use --wait 0 to return immediately.
+
+Examples:
+  alexacli music play "miles davis" -d Kitchen
+  alexacli music play "jazz radio" -d Everywhere --provider tunein
+  alexacli music play "discover weekly" -d Office --provider spotify`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			providerID, err := api.MusicProviderID(provider)
+			if err != nil {
+				return err
+			}
+
+			client, dev, err := deviceClient(ctx, flags, device)
+			if err != nil {
+				return err
+			}
+
+			// Note what was playing so the old track isn't mistaken for the new one.
+			// Some players can't report it, which is no reason not to play.
+			before := &api.NowPlaying{}
+			if wait > 0 {
+				if np, err := client.GetNowPlaying(ctx, dev); err != nil {
+					client.Logger().Debug("failed to read player before playing", "device", dev.AccountName, "error", err)
+				} else {
+					before = np
+				}
+			}
+
+			phrase := strings.Join(args, " ")
+			if err := client.RunSequence(ctx, api.NewSequence(client.PlayMusicOperation(dev, providerID, phrase))); err != nil {
+				return err
+			}
+
+			var np *api.NowPlaying
+			if wait > 0 {
+				if np, err = waitForPlayback(ctx, client, dev, before, wait); err != nil {
+					return err
+				}
+			}
+
+			if flags.asJSON {
+				return out.Data(map[string]interface{}{
+					"device":     dev.AccountName,
+					"phrase":     phrase,
+					"provider":   providerID,
+					"nowPlaying": np,
+				})
+			}
+
+			if wait <= 0 {
+				return out.Success(fmt.Sprintf("Playing %q on %s", phrase, dev.AccountName))
+			}
+			if np == nil {
+				return out.Success(fmt.Sprintf("Asked %s to play %q, but nothing new was playing after %s", dev.AccountName, phrase, wait))
+			}
+			track := np.Title
+			if np.Artist != "" {
+				track += " - " + np.Artist
+			}
+			if np.Provider != "" {
+				track += " [" + np.Provider + "]"
+			}
+			return out.Success(fmt.Sprintf("Now playing on %s: %s", dev.AccountName, track))
+		},
+	}
+
+	cmd.Flags().StringVarP(&device, "device", "d", "", "Device or multi-room group name (default from config)")
+	cmd.Flags().StringVarP(&provider, "provider", "p", "amazon", "Music provider")
+	cmd.Flags().DurationVar(&wait, "wait", 5*time.Second, "How long to wait for playback to start (0 to skip)")
+
+	return cmd
+}
+
+// waitForPlayback polls the player until dev reports a track other than the one
+// playing before. It returns nil if that hasn't happened by the time timeout has
+// passed, since whatever is playing then may still be the old track.
+func waitForPlayback(ctx context.Context, client *api.Client, dev *api.Device, before *api.NowPlaying, timeout time.Duration) (*api.NowPlaying, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if err := api.SleepContext(ctx, time.Second); err != nil {
+			return nil, err
+		}
+
+		np, err := client.GetNowPlaying(ctx, dev)
+		if err != nil {
+			return nil, err
+		}
+
+		playing := np.State == "PLAYING" && np.Title != ""
+		changed := before.State != "PLAYING" || np.Title != before.Title
+		if playing && changed {
+			return np, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newNotifyCmd(flags *rootFlags) *cobra.Command {
+	var title string
+
+	cmd := &cobra.Command{
+		Use:   "notify <message>",
+		Short: "Send a push notification to the Alexa app",
+		Long: `Send a push notification to the Alexa app on the phones signed in to
+this account. Nothing is spoken on any device.
+
+Examples:
+  alexacli notify "Nightly build failed"
+  alexacli notify "Deploy finished" --title CI`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			devices, err := client.GetDevices(ctx)
+			if err != nil {
+				return err
+			}
+
+			if len(devices) == 0 {
+				return fmt.Errorf("%w: no devices on this account", api.ErrDeviceNotFound)
+			}
+
+			// Use first device to supply the customer ID
+			message := strings.Join(args, " ")
+			node := client.PushOperation(&devices[0], title, message)
+			if err := client.RunSequence(ctx, api.NewSequence(node)); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Sent notification: %s", message))
+		},
+	}
+
+	cmd.Flags().StringVarP(&title, "title", "t", "alexacli", "Notification title")
+
+	return cmd
+}
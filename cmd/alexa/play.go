@@ -34,6 +34,7 @@ Examples:
   alexacli play ~/audio.mp3 -d "Kitchen"`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
 			if url == "" && len(args) == 0 {
@@ -56,7 +57,7 @@ Examples:
 
 				// Convert to Alexa-compatible format
 				outFile := filepath.Join(os.TempDir(), "alexa-audio-converted.mp3")
-				convertCmd := exec.Command("ffmpeg", "-i", localFile, "-ar", "22050", "-ab", "48k", "-ac", "1", outFile, "-y")
+				convertCmd := exec.CommandContext(ctx, "ffmpeg", "-i", localFile, "-ar", "22050", "-ab", "48k", "-ac", "1", outFile, "-y")
 				if output, err := convertCmd.CombinedOutput(); err != nil {
 					return fmt.Errorf("failed to convert audio: %v\n%s", err, string(output))
 				}
@@ -70,19 +71,19 @@ Examples:
 				return fmt.Errorf("device is required (use -d)")
 			}
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
-			dev, err := findDevice(client, device)
+			dev, err := findDevice(ctx, client, device)
 			if err != nil {
 				return err
 			}
 
 			// Send SSML with audio tag
 			ssml := fmt.Sprintf(`<speak><audio src="%s"/></speak>`, audioURL)
-			if err := client.SequenceCommand(dev, fmt.Sprintf("speak:'%s'", ssml)); err != nil {
+			if err := client.SequenceCommand(ctx, dev, fmt.Sprintf("speak:'%s'", ssml)); err != nil {
 				return err
 			}
 
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"strings"
 
 	"github.com/buddyh/alexa-cli/internal/api"
 	"github.com/buddyh/alexa-cli/internal/config"
+	"github.com/buddyh/alexa-cli/internal/fake"
 	"github.com/buddyh/alexa-cli/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -14,9 +19,19 @@ import (
 type rootFlags struct {
 	asJSON  bool
 	verbose bool
+	retries int
+	rate    float64
+	fake    bool
+	record  string
+	replay  string
+	dryRun  bool
+
+	// onPlanned, when set, receives requests kept back by --dry-run instead of
+	// them being printed, for commands that report them in their own output
+	onPlanned func(api.PlannedRequest)
 }
 
-func execute(args []string) error {
+func execute(ctx context.Context, args []string) error {
 	flags := &rootFlags{}
 
 	rootCmd := &cobra.Command{
@@ -34,47 +49,121 @@ Get started by running 'alexacli auth' to configure your refresh token.`,
 
 	rootCmd.PersistentFlags().BoolVar(&flags.asJSON, "json", false, "Output as JSON")
 	rootCmd.PersistentFlags().BoolVarP(&flags.verbose, "verbose", "v", false, "Enable verbose debug output")
+	rootCmd.PersistentFlags().IntVar(&flags.retries, "retries", api.DefaultRetryPolicy.MaxRetries, "Retries for network errors, throttling and 5xx responses")
+	rootCmd.PersistentFlags().Float64Var(&flags.rate, "rate", api.DefaultRateLimit.PerSecond, "Maximum requests per second to Amazon (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&flags.record, "record", "", "Record all HTTP traffic, with credentials redacted, to a HAR file")
+	rootCmd.PersistentFlags().StringVar(&flags.replay, "replay", "", "Answer requests from a HAR file made with --record instead of the network")
+	rootCmd.PersistentFlags().BoolVar(&flags.fake, "fake", false, "Run against a built-in fake Alexa backend (also ALEXA_FAKE=1)")
+	rootCmd.PersistentFlags().BoolVar(&flags.dryRun, "dry-run", false, "Print requests that would change anything instead of sending them")
 
 	// Add commands
 	rootCmd.AddCommand(newAuthCmd(flags))
 	rootCmd.AddCommand(newDevicesCmd(flags))
 	rootCmd.AddCommand(newSpeakCmd(flags))
+	rootCmd.AddCommand(newNotifyCmd(flags))
 	rootCmd.AddCommand(newCommandCmd(flags))
+	rootCmd.AddCommand(newChainCmd(flags))
+	rootCmd.AddCommand(newSequenceCmd(flags))
 	rootCmd.AddCommand(newAskCmd(flags))
 	rootCmd.AddCommand(newAskPlusCmd(flags))
 	rootCmd.AddCommand(newConversationsCmd(flags))
 	rootCmd.AddCommand(newFragmentsCmd(flags))
 	rootCmd.AddCommand(newHistoryCmd(flags))
 	rootCmd.AddCommand(newPlayCmd(flags))
+	rootCmd.AddCommand(newVolumeCmd(flags))
+	rootCmd.AddCommand(newMediaCmd(flags))
+	rootCmd.AddCommand(newMusicCmd(flags))
+	rootCmd.AddCommand(newSkillCmd(flags))
+	rootCmd.AddCommand(newStopCmd(flags))
+	rootCmd.AddCommand(newSoundsCmd(flags))
 	rootCmd.AddCommand(newRoutineCmd(flags))
 	rootCmd.AddCommand(newSmartHomeCmd(flags))
+	rootCmd.AddCommand(newAPICmd(flags))
 
 	rootCmd.SetArgs(args)
-	return rootCmd.Execute()
+	return rootCmd.ExecuteContext(ctx)
 }
 
-// getClient creates an authenticated Alexa API client
-func getClient() (*api.Client, error) {
-	return getClientWithFlags(nil)
-}
+// getClientWithFlags creates an authenticated Alexa API client configured from the root flags
+func getClientWithFlags(ctx context.Context, flags *rootFlags) (*api.Client, error) {
+	retry := api.DefaultRetryPolicy
+	retry.MaxRetries = max(flags.retries, 0)
+	limit := api.DefaultRateLimit
+	limit.PerSecond = flags.rate
+
+	opts := api.Options{
+		Retry:      &retry,
+		RateLimit:  &limit,
+		RecordPath: flags.record,
+		ReplayPath: flags.replay,
+	}
+	if flags.dryRun {
+		out := getFormatter(flags)
+		opts.DryRun = func(req api.PlannedRequest) {
+			if flags.onPlanned != nil {
+				flags.onPlanned(req)
+				return
+			}
+			printPlanned(out, flags, req)
+		}
+	}
+	if flags.verbose {
+		// Logs go to stderr so they never mix with --json output on stdout
+		opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	// The fake backend lives as long as the process, so it is never closed
+	if flags.fake || os.Getenv("ALEXA_FAKE") != "" {
+		srv := fake.NewDemoServer()
+		opts.Endpoints = srv.Endpoints()
+		return api.NewClientWithOptions(ctx, "fake-refresh-token", "amazon.com", opts)
+	}
+
+	// A replay needs no account, and neither it nor a recording should touch the
+	// session cache: recordings must include the login so they replay on their own
+	if flags.replay != "" {
+		return api.NewClientWithOptions(ctx, "replay-refresh-token", "amazon.com", opts)
+	}
 
-// getClientWithFlags creates an authenticated Alexa API client with optional flags
-func getClientWithFlags(flags *rootFlags) (*api.Client, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := api.NewClient(cfg.RefreshToken, cfg.AmazonDomain)
+	sessionPath, err := config.SessionPath()
 	if err != nil {
 		return nil, err
 	}
 
-	if flags != nil && flags.verbose {
-		client.SetVerbose(true)
+	if flags.record == "" {
+		opts.SessionPath = sessionPath
+	}
+	opts.Endpoints = cfg.Endpoints
+	return api.NewClientWithOptions(ctx, cfg.RefreshToken, cfg.AmazonDomain, opts)
+}
+
+// printPlanned shows a request that --dry-run kept from being sent
+func printPlanned(out *output.Formatter, flags *rootFlags, req api.PlannedRequest) {
+	if flags.asJSON {
+		_ = out.Data(map[string]interface{}{"dryRun": req})
+		return
 	}
 
-	return client, nil
+	fmt.Printf("Would send %s %s\n", req.Method, req.URL)
+	fmt.Println(indentJSON(req.Body))
+	if req.Sequence != nil {
+		fmt.Println("sequenceJson:")
+		fmt.Println(indentJSON(req.Sequence))
+	}
+}
+
+// indentJSON pretty-prints data, or returns it as-is if it isn't valid JSON
+func indentJSON(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
 }
 
 // getFormatter creates an output formatter
@@ -82,27 +171,57 @@ func getFormatter(flags *rootFlags) *output.Formatter {
 	return output.NewFormatter(os.Stdout, flags.asJSON)
 }
 
-// findDevice finds a device by name or serial
-func findDevice(client *api.Client, nameOrSerial string) (*api.Device, error) {
-	devices, err := client.GetDevices()
+// deviceOrDefault returns device, or the default_device from the config file if it is empty
+func deviceOrDefault(device string) (string, error) {
+	if device != "" {
+		return device, nil
+	}
+
+	cfg, err := config.ReadFile()
+	if err != nil {
+		return "", err
+	}
+	if cfg.DeviceSerial == "" {
+		return "", fmt.Errorf("device is required (use -d or set default_device in the config file)")
+	}
+	return cfg.DeviceSerial, nil
+}
+
+// deviceClient returns a client and the device named by -d, or the default device
+func deviceClient(ctx context.Context, flags *rootFlags, device string) (*api.Client, *api.Device, error) {
+	device, err := deviceOrDefault(device)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Try exact match first
-	for i, d := range devices {
-		if d.SerialNumber == nameOrSerial || d.AccountName == nameOrSerial {
-			return &devices[i], nil
-		}
+	client, err := getClientWithFlags(ctx, flags)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Try case-insensitive partial match
-	nameLower := strings.ToLower(nameOrSerial)
-	for i, d := range devices {
-		if strings.Contains(strings.ToLower(d.AccountName), nameLower) {
-			return &devices[i], nil
-		}
+	dev, err := findDevice(ctx, client, device)
+	if err != nil {
+		return nil, nil, err
 	}
+	return client, dev, nil
+}
 
-	return nil, fmt.Errorf("device '%s' not found", nameOrSerial)
+// findDevice finds a device by name or serial, failing if Amazon reports it offline
+func findDevice(ctx context.Context, client *api.Client, nameOrSerial string) (*api.Device, error) {
+	dev, err := client.FindDevice(ctx, nameOrSerial)
+	if err != nil {
+		return nil, err
+	}
+	if !dev.Online {
+		return nil, fmt.Errorf("%w: '%s'", api.ErrDeviceOffline, dev.AccountName)
+	}
+	return dev, nil
+}
+
+// readInput reads a file given on the command line, or stdin for -
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
 }
@@ -1,21 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/buddyh/alexa-cli/internal/api"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func newRoutineCmd(flags *rootFlags) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "routine",
 		Short: "Manage Alexa routines",
-		Long:  `List and execute Alexa routines.`,
+		Long: `List, inspect, execute and edit Alexa routines.
+
+Routines can be created and updated from YAML or JSON files, so the same
+routine can be kept in several homes:
+
+  name: Morning
+  device: Kitchen            # where steps run unless they say otherwise
+  triggers:
+    - say: good morning      # "Alexa, good morning"
+    - at: "07:00"
+      repeat: weekdays       # daily, weekdays, weekends or e.g. mon,wed
+  steps:
+    - volume: 30
+    - speak: Good morning
+    - weather
+    - wait: 5s
+    - parallel:
+        - command: turn on the kitchen lights
+        - speak: Breakfast time
+          device: Office
+
+Steps are the same as for 'alexacli chain'. serial and parallel groups
+can be nested.`,
 	}
 
 	cmd.AddCommand(newRoutineListCmd(flags))
+	cmd.AddCommand(newRoutineShowCmd(flags))
 	cmd.AddCommand(newRoutineRunCmd(flags))
+	cmd.AddCommand(newRoutineCreateCmd(flags))
+	cmd.AddCommand(newRoutineUpdateCmd(flags))
+	cmd.AddCommand(newRoutineDeleteCmd(flags))
+	cmd.AddCommand(newRoutineEnableCmd(flags, "enable", true))
+	cmd.AddCommand(newRoutineEnableCmd(flags, "disable", false))
+	cmd.AddCommand(newRoutineExportCmd(flags))
+	cmd.AddCommand(newRoutineImportCmd(flags))
 
 	return cmd
 }
@@ -25,14 +63,15 @@ func newRoutineListCmd(flags *rootFlags) *cobra.Command {
 		Use:   "list",
 		Short: "List available routines",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
-			routines, err := client.GetRoutines()
+			routines, err := client.GetRoutines(ctx)
 			if err != nil {
 				return err
 			}
@@ -53,6 +92,138 @@ func newRoutineListCmd(flags *rootFlags) *cobra.Command {
 	}
 }
 
+func newRoutineShowCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <routine-name>",
+		Short: "Show what a routine does",
+		Long: `Show a routine's triggers and actions as an outline.
+
+With --json the routine is printed as a normalised structure of
+triggers and nested steps.
+
+Examples:
+  alexacli routine show "Good Night"
+  alexacli routine show "Good Night" --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+
+			routine, err := api.MatchRoutine(routines, args[0])
+			if err != nil {
+				return err
+			}
+
+			// Devices are only needed to put names to serials
+			devices, err := client.GetDevices(ctx)
+			if err != nil {
+				return err
+			}
+
+			details, err := api.ParseRoutine(*routine, devices)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(details)
+			}
+
+			status := ""
+			if details.Status != "" && details.Status != "ENABLED" {
+				status = " (" + strings.ToLower(details.Status) + ")"
+			}
+			fmt.Printf("%s%s\n", details.Name, status)
+
+			fmt.Println("  When:")
+			if len(details.Triggers) == 0 {
+				fmt.Println("    (no triggers)")
+			}
+			for _, t := range details.Triggers {
+				fmt.Printf("    %s\n", describeTrigger(t))
+			}
+
+			fmt.Println("  Do:")
+			if details.Steps == nil {
+				fmt.Println("    (no actions)")
+				return nil
+			}
+			printStep(*details.Steps, "    ")
+			return nil
+		},
+	}
+}
+
+// describeTrigger renders a routine trigger as one line
+func describeTrigger(t api.RoutineTrigger) string {
+	var s string
+	switch t.Kind {
+	case "utterance":
+		quoted := make([]string, len(t.Utterances))
+		for i, u := range t.Utterances {
+			quoted[i] = fmt.Sprintf("%q", u)
+		}
+		s = "You say " + strings.Join(quoted, " or ")
+	case "schedule":
+		s = "At " + t.Time
+		if t.Recurrence != "" {
+			s += ", " + t.Recurrence
+		}
+		if t.TimeZone != "" {
+			s += " (" + t.TimeZone + ")"
+		}
+	default:
+		s = "Event " + t.Type
+	}
+	if len(t.Devices) > 0 {
+		s += " on " + strings.Join(t.Devices, ", ")
+	}
+	return s
+}
+
+// printStep prints a routine step and its children as an indented outline
+func printStep(step api.RoutineStep, indent string) {
+	switch step.Kind {
+	case "serial", "parallel":
+		label := "In order:"
+		if step.Kind == "parallel" {
+			label = "At the same time:"
+		}
+		fmt.Printf("%s%s\n", indent, label)
+		for _, child := range step.Steps {
+			printStep(child, indent+"  ")
+		}
+		return
+	}
+
+	if step.Operation == "Alexa.System.Wait" {
+		fmt.Printf("%sWait %ds\n", indent, step.DelaySeconds)
+		return
+	}
+
+	line := step.Operation
+	if step.Text != "" {
+		line += fmt.Sprintf(" %q", step.Text)
+	}
+	if step.Value != "" {
+		line += " " + step.Value
+	}
+	if len(step.Devices) > 0 {
+		line += " on " + strings.Join(step.Devices, ", ")
+	}
+	fmt.Printf("%s%s\n", indent, line)
+}
+
 func newRoutineRunCmd(flags *rootFlags) *cobra.Command {
 	var device string
 
@@ -66,9 +237,10 @@ Examples:
   alexacli routine run "Morning Routine" -d Kitchen`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			apiClient, err := getClient()
+			apiClient, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
@@ -78,23 +250,23 @@ Examples:
 			// Get a device to use for execution
 			var dev *api.Device
 			if device != "" {
-				dev, err = findDevice(apiClient, device)
+				dev, err = findDevice(ctx, apiClient, device)
 				if err != nil {
 					return err
 				}
 			} else {
 				// Use first available device
-				devices, err := apiClient.GetDevices()
+				devices, err := apiClient.GetDevices(ctx)
 				if err != nil {
 					return err
 				}
 				if len(devices) == 0 {
-					return fmt.Errorf("no devices found")
+					return fmt.Errorf("%w: no devices on this account", api.ErrDeviceNotFound)
 				}
 				dev = &devices[0]
 			}
 
-			if err := apiClient.ExecuteRoutine(dev, routineName); err != nil {
+			if err := apiClient.ExecuteRoutine(ctx, dev, routineName); err != nil {
 				return err
 			}
 
@@ -106,3 +278,509 @@ Examples:
 
 	return cmd
 }
+
+func newRoutineCreateCmd(flags *rootFlags) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "create -f <file>",
+		Short: "Create a routine from a file",
+		Long: `Create a routine from a YAML or JSON file (- for stdin).
+See 'alexacli routine --help' for the file format.
+
+Examples:
+  alexacli routine create -f morning.yaml
+  alexacli routine create -f morning.yaml --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			spec, err := readRoutineSpec(file)
+			if err != nil {
+				return err
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+			if _, err := api.MatchRoutine(routines, spec.Name); err == nil {
+				return fmt.Errorf("routine '%s' already exists (use 'alexacli routine update')", spec.Name)
+			}
+
+			routine, err := buildRoutine(cmd, client, spec)
+			if err != nil {
+				return err
+			}
+
+			id, err := client.CreateRoutine(ctx, routine)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(map[string]string{"automationId": id, "name": routine.Name})
+			}
+			return out.Success(fmt.Sprintf("Created routine: %s", routine.Name))
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Routine file, YAML or JSON (- for stdin)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func newRoutineUpdateCmd(flags *rootFlags) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "update [routine-name] -f <file>",
+		Short: "Replace a routine with the contents of a file",
+		Long: `Replace a routine's name, triggers and steps with a YAML or JSON file
+(- for stdin). The routine to replace is the one named in the file, or
+routine-name if given, which allows renaming it.
+
+Examples:
+  alexacli routine update -f morning.yaml
+  alexacli routine update "Morning" -f wake-up.yaml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			spec, err := readRoutineSpec(file)
+			if err != nil {
+				return err
+			}
+
+			name := spec.Name
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+			existing, err := api.MatchRoutine(routines, name)
+			if err != nil {
+				return err
+			}
+
+			routine, err := buildRoutine(cmd, client, spec)
+			if err != nil {
+				return err
+			}
+			routine.AutomationID = existing.AutomationID
+			routine.Raw = existing.Raw // keep settings the file doesn't cover
+
+			if err := client.UpdateRoutine(ctx, routine); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Updated routine: %s", routine.Name))
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Routine file, YAML or JSON (- for stdin)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func newRoutineDeleteCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <routine-name>",
+		Short: "Delete a routine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+			routine, err := api.MatchRoutine(routines, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := client.DeleteRoutine(ctx, routine.AutomationID); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Deleted routine: %s", routine.Name))
+		},
+	}
+}
+
+func newRoutineEnableCmd(flags *rootFlags, use string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <routine-name>",
+		Short: strings.ToUpper(use[:1]) + use[1:] + " a routine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+			routine, err := api.MatchRoutine(routines, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := client.SetRoutineEnabled(ctx, routine, enabled); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("%sd routine: %s", strings.ToUpper(use[:1])+use[1:], routine.Name))
+		},
+	}
+}
+
+// buildRoutine converts a routine file into the routine Amazon stores
+func buildRoutine(cmd *cobra.Command, client *api.Client, spec *api.RoutineSpec) (*api.Routine, error) {
+	devices, err := client.GetDevices(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	routine, err := client.BuildRoutine(spec, devices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routine: %w", err)
+	}
+	return routine, nil
+}
+
+// readRoutineSpec reads a routine file. YAML is a superset of JSON, so both are
+// parsed as YAML and then decoded like JSON, which catches misspelt fields.
+func readRoutineSpec(path string) (*api.RoutineSpec, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routine file: %w", err)
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid routine file %s: %w", path, err)
+	}
+	asJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routine file %s: %w", path, err)
+	}
+
+	var spec api.RoutineSpec
+	dec := json.NewDecoder(bytes.NewReader(asJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid routine file %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+func newRoutineExportCmd(flags *rootFlags) *cobra.Command {
+	var all bool
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "export [routine-name] -o <dir>",
+		Short: "Save routines to files",
+		Long: `Write routines to JSON files, one per routine, for backup or to copy
+them to another account with 'alexacli routine import'.
+
+Each file holds the full automation definition plus the names of the
+devices it uses, so it can be restored after an Echo is replaced.
+
+Examples:
+  alexacli routine export --all -o backup/
+  alexacli routine export "Good Night" -o backup/`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			if all == (len(args) == 1) {
+				return fmt.Errorf("give a routine name or --all")
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+			if !all {
+				routine, err := api.MatchRoutine(routines, args[0])
+				if err != nil {
+					return err
+				}
+				routines = []api.Routine{*routine}
+			}
+
+			devices, err := client.GetDevices(ctx)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+
+			used := make(map[string]bool)
+			var files []string
+			for _, r := range routines {
+				exp, err := api.ExportRoutine(r, devices)
+				if err != nil {
+					return err
+				}
+
+				data, err := json.MarshalIndent(exp, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				path := filepath.Join(dir, exportFileName(r, used))
+				if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+				files = append(files, path)
+
+				if !flags.asJSON {
+					fmt.Printf("Exported %s to %s\n", r.Name, path)
+				}
+			}
+
+			if flags.asJSON {
+				return out.Data(map[string]interface{}{"files": files})
+			}
+			if len(files) == 0 {
+				return out.Success("No routines found")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Export every routine")
+	cmd.Flags().StringVarP(&dir, "output", "o", ".", "Directory to write the files to")
+
+	return cmd
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFileName picks a file name for a routine that no other routine in this export uses
+func exportFileName(r api.Routine, used map[string]bool) string {
+	base := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(r.Name), "-"), "-")
+	if base == "" {
+		base = "routine"
+	}
+
+	name := base + ".json"
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d.json", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+func newRoutineImportCmd(flags *rootFlags) *cobra.Command {
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file|dir>...",
+		Short: "Restore routines saved with export",
+		Long: `Recreate routines from files written by 'alexacli routine export'. A
+directory imports every .json file in it.
+
+Devices are matched by name, so routines can be restored to another
+account or after replacing an Echo with one of the same name. Devices
+that can't be matched are reported and left as they were in the file.
+
+Routines that already exist are skipped unless --replace is given.
+
+Examples:
+  alexacli routine import backup/
+  alexacli routine import backup/good-night.json --replace
+  alexacli routine import backup/ --dry-run`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			files, err := exportFiles(args)
+			if err != nil {
+				return err
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			routines, err := client.GetRoutines(ctx)
+			if err != nil {
+				return err
+			}
+
+			devices, err := client.GetDevices(ctx)
+			if err != nil {
+				return err
+			}
+
+			type importResult struct {
+				File     string              `json:"file"`
+				Name     string              `json:"name,omitempty"`
+				Action   string              `json:"action"` // created, replaced, skipped or failed
+				Unmapped []string            `json:"unmappedDevices,omitempty"`
+				Error    string              `json:"error,omitempty"`
+				Planned  *api.PlannedRequest `json:"dryRun,omitempty"` // the request --dry-run kept back
+				dryRun   bool
+			}
+
+			var results []importResult
+			var failed []error
+			dryRun := false
+			for _, file := range files {
+				res := importResult{File: file}
+				if flags.asJSON {
+					// Keep the planned request with its routine so the output is one JSON document
+					flags.onPlanned = func(req api.PlannedRequest) { res.Planned = &req }
+				}
+				err := func() error {
+					data, err := os.ReadFile(file)
+					if err != nil {
+						return err
+					}
+					var exp api.RoutineExport
+					if err := json.Unmarshal(data, &exp); err != nil {
+						return fmt.Errorf("not a routine export: %w", err)
+					}
+					res.Name = exp.Name
+
+					existing, _ := api.MatchRoutine(routines, exp.Name)
+					if existing != nil && !replace {
+						res.Action = "skipped"
+						return nil
+					}
+
+					routine, unmapped, err := client.RemapRoutine(&exp, devices)
+					if err != nil {
+						return err
+					}
+					res.Unmapped = unmapped
+
+					if existing != nil {
+						res.Action = "replaced"
+						routine.AutomationID = existing.AutomationID
+						return client.UpdateRoutine(ctx, routine)
+					}
+					res.Action = "created"
+					_, err = client.CreateRoutine(ctx, routine)
+					return err
+				}()
+				// A dry run shows each request and carries on with the next file
+				if errors.Is(err, api.ErrDryRun) {
+					res.dryRun = true
+					dryRun = true
+					err = nil
+				}
+				if err != nil {
+					res.Action = "failed"
+					res.Error = err.Error()
+					failed = append(failed, fmt.Errorf("%s: %w", file, err))
+				}
+				results = append(results, res)
+			}
+
+			if flags.asJSON {
+				if err := out.Data(results); err != nil {
+					return err
+				}
+			} else {
+				for _, r := range results {
+					switch r.Action {
+					case "failed":
+						fmt.Printf("Failed to import %s: %s\n", r.File, r.Error)
+					case "skipped":
+						fmt.Printf("Skipped %s: already exists (use --replace)\n", r.Name)
+					case "created", "replaced":
+						verb := strings.ToUpper(r.Action[:1]) + r.Action[1:]
+						if r.dryRun {
+							verb = "Would " + strings.TrimSuffix(r.Action, "d")
+						}
+						fmt.Printf("%s %s\n", verb, r.Name)
+					}
+					for _, u := range r.Unmapped {
+						fmt.Printf("  Could not remap device %s\n", u)
+					}
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to import %d of %d routine(s): %w", len(failed), len(results), failed[0])
+			}
+			if dryRun {
+				return api.ErrDryRun
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&replace, "replace", false, "Replace routines that already exist")
+
+	return cmd
+}
+
+// exportFiles expands directories in paths to the .json files in them
+func exportFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no .json files in %s", path)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
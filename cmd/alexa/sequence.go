@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newSequenceCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sequence",
+		Short: "Run raw behavior sequences",
+		Long:  `Run com.amazon.alexa.behaviors.model.Sequence documents directly, for sequences the other commands can't express.`,
+	}
+
+	cmd.AddCommand(newSequenceRunCmd(flags))
+
+	return cmd
+}
+
+func newSequenceRunCmd(flags *rootFlags) *cobra.Command {
+	var device string
+
+	cmd := &cobra.Command{
+		Use:   "run <file.json|->",
+		Short: "Run a sequence from a JSON file",
+		Long: `Run a complete sequence document (@type
+com.amazon.alexa.behaviors.model.Sequence) from a file, or - for stdin.
+
+The document may use placeholders that are filled from -d:
+  ${device.serial}       Serial number
+  ${device.type}         Device type
+  ${device.customerId}   Customer ID of the account
+  ${device.locale}       Locale, e.g. en-US
+  ${device.name}         Device name
+
+Without -d, the default_device from the config file is used when the
+document has placeholders. Use --dry-run to see the filled-in sequence
+without running it.
+
+Examples:
+  alexacli sequence run morning.json -d Kitchen
+  alexacli sequence run - -d Office < sequence.json
+  alexacli sequence run morning.json -d Kitchen --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			doc, err := readInput(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read sequence: %w", err)
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			// Only look up a device if one was asked for or the document needs one
+			var dev *api.Device
+			if device != "" || bytes.Contains(doc, []byte("${device.")) {
+				name, err := deviceOrDefault(device)
+				if err != nil {
+					return err
+				}
+				if dev, err = findDevice(ctx, client, name); err != nil {
+					return err
+				}
+			}
+
+			doc, err = client.ExpandDeviceVars(doc, dev)
+			if err != nil {
+				return err
+			}
+
+			if err := client.RunSequenceJSON(ctx, doc); err != nil {
+				return err
+			}
+
+			if dev != nil {
+				return out.Success(fmt.Sprintf("Ran sequence from %s on %s", args[0], dev.AccountName))
+			}
+			return out.Success(fmt.Sprintf("Ran sequence from %s", args[0]))
+		},
+	}
+
+	cmd.Flags().StringVarP(&device, "device", "d", "", "Device for ${device.*} placeholders (default from config)")
+
+	return cmd
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+func newSkillCmd(flags *rootFlags) *cobra.Command {
+	var device string
+
+	cmd := &cobra.Command{
+		Use:   "skill",
+		Short: "Run built-in Alexa skills",
+		Long: `Run Alexa's built-in skills on a device.
+
+These send the native operation rather than a text command, so they don't
+depend on Alexa interpreting the wording. Without -d, the default_device
+from the config file is used.
+
+Examples:
+  alexacli skill weather -d Kitchen
+  alexacli skill flash-briefing -d Bedroom
+  alexacli skill joke -d Office`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&device, "device", "d", "", "Device name or serial (default from config)")
+
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "weather", "Play the weather forecast", api.OpWeather))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "traffic", "Play the traffic report", api.OpTraffic))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "flash-briefing", "Play the flash briefing", api.OpFlashBriefing))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "good-morning", "Play the good morning greeting", api.OpGoodMorning))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "joke", "Tell a joke", api.OpJoke))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "fun-fact", "Tell a fun fact", api.OpFunFact))
+	cmd.AddCommand(newSkillOpCmd(flags, &device, "sing", "Sing a song", api.OpSingASong))
+
+	return cmd
+}
+
+func newSkillOpCmd(flags *rootFlags, device *string, use, short, opType string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, dev, err := deviceClient(ctx, flags, *device)
+			if err != nil {
+				return err
+			}
+
+			if err := client.RunSequence(ctx, api.NewSequence(client.DeviceOperation(dev, opType))); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Ran %s on %s", use, dev.AccountName))
+		},
+	}
+}
+
+func newStopCmd(flags *rootFlags) *cobra.Command {
+	var devices []string
+
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop whatever is playing",
+		Long: `Stop music, timers sounding, speech and anything else playing.
+
+Without -d, every device in the household is stopped.
+
+Examples:
+  alexacli stop
+  alexacli stop -d Kitchen -d Office`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			var targets []*api.Device
+			var names []string
+			for _, name := range devices {
+				dev, err := findDevice(ctx, client, name)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, dev)
+				names = append(names, dev.AccountName)
+			}
+
+			if len(targets) == 0 {
+				all, err := client.GetDevices(ctx)
+				if err != nil {
+					return err
+				}
+				if len(all) == 0 {
+					return fmt.Errorf("%w: no devices on this account", api.ErrDeviceNotFound)
+				}
+
+				// Use first device to supply the customer ID
+				if err := client.RunSequence(ctx, api.NewSequence(client.StopOperation(&all[0]))); err != nil {
+					return err
+				}
+				return out.Success("Stopped all devices")
+			}
+
+			if err := client.RunSequence(ctx, api.NewSequence(client.StopOperation(targets[0], targets...))); err != nil {
+				return err
+			}
+			return out.Success(fmt.Sprintf("Stopped %s", strings.Join(names, ", ")))
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&devices, "device", "d", nil, "Device name or serial (repeatable; default all devices)")
+
+	return cmd
+}
+
+func newSoundsCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sounds",
+		Short: "Browse and play the Alexa sound library",
+		Long: `Browse the Alexa sound library and play sounds on a device.
+
+Examples:
+  alexacli sounds list
+  alexacli sounds play amzn_sfx_doorbell_chime_01 -d Kitchen`,
+	}
+
+	cmd.AddCommand(newSoundsListCmd(flags))
+	cmd.AddCommand(newSoundsPlayCmd(flags))
+
+	return cmd
+}
+
+func newSoundsListCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List sound IDs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, err := getClientWithFlags(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			sounds, err := client.GetSounds(ctx)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(sounds)
+			}
+
+			if len(sounds) == 0 {
+				fmt.Println("No sounds found")
+				return nil
+			}
+
+			for _, s := range sounds {
+				fmt.Printf("%-40s %s\n", s.ID, s.DisplayName)
+			}
+			return nil
+		},
+	}
+}
+
+func newSoundsPlayCmd(flags *rootFlags) *cobra.Command {
+	var device string
+
+	cmd := &cobra.Command{
+		Use:   "play <sound-id>",
+		Short: "Play a sound on a device",
+		Long: `Play a sound from the Alexa sound library on a device.
+
+Use 'alexacli sounds list' to find sound IDs. Without -d, the
+default_device from the config file is used.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, dev, err := deviceClient(ctx, flags, device)
+			if err != nil {
+				return err
+			}
+
+			if err := client.RunSequence(ctx, api.NewSequence(client.SoundOperation(dev, args[0]))); err != nil {
+				return err
+			}
+
+			return out.Success(fmt.Sprintf("Played %s on %s", args[0], dev.AccountName))
+		},
+	}
+
+	cmd.Flags().StringVarP(&device, "device", "d", "", "Device name or serial (default from config)")
+
+	return cmd
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/buddyh/alexa-cli/internal/api"
 	"github.com/spf13/cobra"
@@ -11,9 +11,9 @@ import (
 
 func newSmartHomeCmd(flags *rootFlags) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "smarthome",
-		Short: "Control smart home devices",
-		Long:  `List and control smart home devices connected to Alexa.`,
+		Use:     "smarthome",
+		Short:   "Control smart home devices",
+		Long:    `List and control smart home devices connected to Alexa.`,
 		Aliases: []string{"sh", "home"},
 	}
 
@@ -30,14 +30,15 @@ func newSmartHomeListCmd(flags *rootFlags) *cobra.Command {
 		Use:   "list",
 		Short: "List smart home devices",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
-			devices, err := client.GetSmartHomeDevices()
+			devices, err := client.GetSmartHomeDevices(ctx)
 			if err != nil {
 				return err
 			}
@@ -73,20 +74,21 @@ Examples:
   alexacli sh on "Living Room Lamp"`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			deviceName := args[0]
-			device, err := findSmartDevice(client, deviceName)
+			device, err := findSmartDevice(ctx, client, deviceName)
 			if err != nil {
 				return err
 			}
 
-			if err := client.ControlSmartHome(device.EntityID, "on", nil); err != nil {
+			if err := client.ControlSmartHome(ctx, device.EntityID, "on", nil); err != nil {
 				return err
 			}
 
@@ -106,20 +108,21 @@ Examples:
   alexacli sh off "All Lights"`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			deviceName := args[0]
-			device, err := findSmartDevice(client, deviceName)
+			device, err := findSmartDevice(ctx, client, deviceName)
 			if err != nil {
 				return err
 			}
 
-			if err := client.ControlSmartHome(device.EntityID, "off", nil); err != nil {
+			if err := client.ControlSmartHome(ctx, device.EntityID, "off", nil); err != nil {
 				return err
 			}
 
@@ -141,9 +144,10 @@ Examples:
   alexacli sh brightness "Bedroom Lamp" 75`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClient()
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
@@ -158,12 +162,12 @@ Examples:
 				return fmt.Errorf("brightness must be 0-100")
 			}
 
-			device, err := findSmartDevice(client, deviceName)
+			device, err := findSmartDevice(ctx, client, deviceName)
 			if err != nil {
 				return err
 			}
 
-			if err := client.ControlSmartHome(device.EntityID, "brightness", level); err != nil {
+			if err := client.ControlSmartHome(ctx, device.EntityID, "brightness", level); err != nil {
 				return err
 			}
 
@@ -173,25 +177,6 @@ Examples:
 }
 
 // findSmartDevice finds a smart home device by name
-func findSmartDevice(client *api.Client, name string) (*api.SmartHomeDevice, error) {
-	devices, err := client.GetSmartHomeDevices()
-	if err != nil {
-		return nil, err
-	}
-
-	nameLower := strings.ToLower(name)
-	for i, d := range devices {
-		if strings.ToLower(d.Name) == nameLower {
-			return &devices[i], nil
-		}
-	}
-
-	// Try partial match
-	for i, d := range devices {
-		if strings.Contains(strings.ToLower(d.Name), nameLower) {
-			return &devices[i], nil
-		}
-	}
-
-	return nil, fmt.Errorf("smart home device '%s' not found", name)
+func findSmartDevice(ctx context.Context, client *api.Client, name string) (*api.SmartHomeDevice, error) {
+	return client.FindSmartHomeDevice(ctx, name)
 }
@@ -1,77 +1,294 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/buddyh/alexa-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
 func newSpeakCmd(flags *rootFlags) *cobra.Command {
-	var device string
+	var devices []string
+	var groups []string
+	var allOnline bool
 	var announce bool
+	var title string
+	var expire time.Duration
+	var volume int
+	var temporary bool
 
 	cmd := &cobra.Command{
 		Use:   "speak <text>",
 		Short: "Make Alexa speak text",
 		Long: `Make an Alexa device speak the provided text using text-to-speech.
 
-Use --announce to broadcast to all devices.
+Repeat -d, use a device group (-g) or --all-online to speak on several
+devices at once; they all start together. Groups are defined in the
+config file, e.g. "groups": {"builds": ["Kitchen", "Office"]}.
+
+Use --announce to broadcast an announcement. Without -d, -g or
+--all-online it plays on every device in the household; with them it
+plays only on the chosen devices.
 
 Examples:
   alexacli speak "Hello world" -d "Kitchen Echo"
   alexacli speak "Dinner is ready" --announce
-  alexacli speak "The build completed successfully" -d Office`,
+  alexacli speak "The build completed successfully" -d Office
+  alexacli speak "Build failed" -d Kitchen -d Office -d Garage
+  alexacli speak "Build passed" -g builds
+  alexacli speak "Time for bed" --all-online
+  alexacli speak "Deploy finished" --announce -d Kitchen -d Office --title "CI"
+  alexacli speak "Wake up" -d Bedroom --volume 70 --temporary`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
 			out := getFormatter(flags)
 
-			client, err := getClientWithFlags(flags)
+			if !announce && (cmd.Flags().Changed("title") || cmd.Flags().Changed("expire")) {
+				return fmt.Errorf("--title and --expire only apply with --announce")
+			}
+			setVolume := cmd.Flags().Changed("volume")
+			if setVolume && announce {
+				return fmt.Errorf("--volume can't be combined with --announce")
+			}
+			if setVolume && (volume < 0 || volume > 100) {
+				return fmt.Errorf("--volume must be 0-100")
+			}
+			if temporary && !setVolume {
+				return fmt.Errorf("--temporary needs --volume")
+			}
+
+			client, err := getClientWithFlags(ctx, flags)
 			if err != nil {
 				return err
 			}
 
 			text := strings.Join(args, " ")
+			targeted := len(devices) > 0 || len(groups) > 0 || allOnline
+			opts := api.AnnouncementOptions{Title: title, ExpireAfter: expire}
 
-			if announce {
+			if announce && !targeted {
 				// Announcement goes to all devices
-				devices, err := client.GetDevices()
+				all, err := client.GetDevices(ctx)
 				if err != nil {
 					return err
 				}
 
-				if len(devices) == 0 {
-					return fmt.Errorf("no devices found")
+				if len(all) == 0 {
+					return fmt.Errorf("%w: no devices on this account", api.ErrDeviceNotFound)
 				}
 
-				// Use first device to trigger announcement
-				if err := client.SequenceCommand(&devices[0], fmt.Sprintf("announcement:'%s'", text)); err != nil {
+				// Use first device to supply the customer ID
+				node := client.AnnouncementOperation(&all[0], text, opts)
+				if err := client.RunSequence(ctx, api.NewSequence(node)); err != nil {
 					return err
 				}
 
 				return out.Success(fmt.Sprintf("Announced: %s", text))
 			}
 
-			// Send to specific device
-			if device == "" {
-				return fmt.Errorf("device is required (use -d or --announce)")
+			if !targeted {
+				return fmt.Errorf("device is required (use -d, -g, --all-online or --announce)")
+			}
+
+			// A single named device keeps the simple path
+			if !announce && !setVolume && len(devices) == 1 && len(groups) == 0 && !allOnline {
+				dev, err := findDevice(ctx, client, devices[0])
+				if err != nil {
+					return err
+				}
+
+				if err := client.SequenceCommand(ctx, dev, fmt.Sprintf("speak:'%s'", text)); err != nil {
+					return err
+				}
+
+				return out.Success(fmt.Sprintf("Spoke on %s: %s", dev.AccountName, text))
 			}
 
-			dev, err := findDevice(client, device)
+			names := devices
+			for _, group := range groups {
+				members, err := config.Group(group)
+				if err != nil {
+					return err
+				}
+				names = append(names, members...)
+			}
+
+			// Remember each device's level so a temporary change can be undone
+			previous := make(map[string]int)
+			if temporary {
+				volumes, err := client.GetVolumes(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to read current volume: %w", err)
+				}
+				for _, v := range volumes {
+					previous[v.SerialNumber] = v.SpeakerVolume
+				}
+			}
+
+			// One announcement targeted at every device, or one Alexa.Speak per device in parallel
+			verb := "Spoke on"
+			build := func(targets []*api.Device) api.Sequence {
+				nodes := make([]api.Node, len(targets))
+				for i, dev := range targets {
+					nodes[i] = client.SpeakOperation(dev, text)
+					if !setVolume {
+						continue
+					}
+
+					steps := api.SerialNode{client.VolumeOperation(dev, volume), nodes[i]}
+					if temporary {
+						if prev, ok := previous[dev.SerialNumber]; ok {
+							steps = append(steps, client.VolumeOperation(dev, prev))
+						} else {
+							fmt.Fprintf(os.Stderr, "Warning: current volume of %s is unknown, so it will stay at %d\n", dev.AccountName, volume)
+						}
+					}
+					nodes[i] = steps
+				}
+				if len(nodes) == 1 {
+					return api.NewSequence(nodes...)
+				}
+				return api.Sequence{Start: api.ParallelNode(nodes)}
+			}
+			if announce {
+				verb = "Announced on"
+				build = func(targets []*api.Device) api.Sequence {
+					opts.Devices = targets
+					return api.NewSequence(client.AnnouncementOperation(targets[0], text, opts))
+				}
+			}
+
+			results, err := runOnDevices(ctx, client, names, allOnline, build)
 			if err != nil {
 				return err
 			}
 
-			if err := client.SequenceCommand(dev, fmt.Sprintf("speak:'%s'", text)); err != nil {
-				return err
+			var failed []error
+			for _, r := range results {
+				if r.err != nil {
+					failed = append(failed, r.err)
+				}
 			}
 
-			return out.Success(fmt.Sprintf("Spoke on %s: %s", dev.AccountName, text))
+			if flags.asJSON {
+				type resultOut struct {
+					Target string `json:"target"`
+					Device string `json:"device,omitempty"`
+					OK     bool   `json:"ok"`
+					Error  string `json:"error,omitempty"`
+				}
+				rows := make([]resultOut, len(results))
+				for i, r := range results {
+					rows[i] = resultOut{Target: r.target, Device: r.device, OK: r.err == nil}
+					if r.err != nil {
+						rows[i].Error = r.err.Error()
+					}
+				}
+				if err := out.Data(map[string]interface{}{"text": text, "announce": announce, "results": rows}); err != nil {
+					return err
+				}
+			} else {
+				for _, r := range results {
+					if r.err != nil {
+						fmt.Printf("Failed on %s: %v\n", r.target, r.err)
+					} else {
+						fmt.Printf("%s %s: %s\n", verb, r.device, text)
+					}
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("failed on %d of %d device(s): %w", len(failed), len(results), failed[0])
+			}
+			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&device, "device", "d", "", "Device name or serial")
-	cmd.Flags().BoolVarP(&announce, "announce", "a", false, "Announce to all devices")
+	cmd.Flags().StringArrayVarP(&devices, "device", "d", nil, "Device name or serial (repeat for several devices)")
+	cmd.Flags().StringArrayVarP(&groups, "group", "g", nil, "Device group from the config file (repeatable)")
+	cmd.Flags().BoolVar(&allOnline, "all-online", false, "Speak on every online device")
+	cmd.Flags().BoolVarP(&announce, "announce", "a", false, "Announce instead of speaking (to all devices unless -d, -g or --all-online)")
+	cmd.Flags().StringVar(&title, "title", "", "Announcement title shown on screen devices (default \"Announcement\")")
+	cmd.Flags().DurationVar(&expire, "expire", 5*time.Second, "How long an announcement may wait to play")
+	cmd.Flags().IntVar(&volume, "volume", 0, "Set the volume (0-100) before speaking")
+	cmd.Flags().BoolVar(&temporary, "temporary", false, "Restore each device's previous volume after speaking (with --volume)")
 
 	return cmd
 }
+
+// deviceResult is the outcome of a command on one requested device
+type deviceResult struct {
+	target string // name or serial as given
+	device string // resolved device name
+	err    error
+}
+
+// runOnDevices resolves every named device (and every online device if allOnline)
+// and runs the single sequence build returns for them, so they all act together.
+// Devices that can't be resolved or are offline are reported and skipped.
+func runOnDevices(ctx context.Context, client *api.Client, names []string, allOnline bool, build func([]*api.Device) api.Sequence) ([]deviceResult, error) {
+	all, err := client.GetDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []deviceResult
+	var targets []*api.Device
+	seen := make(map[string]bool)
+
+	add := func(target string, dev *api.Device) {
+		if seen[dev.SerialNumber] {
+			return
+		}
+		seen[dev.SerialNumber] = true
+		if !dev.Online {
+			results = append(results, deviceResult{target: target, device: dev.AccountName, err: api.ErrDeviceOffline})
+			return
+		}
+		results = append(results, deviceResult{target: target, device: dev.AccountName})
+		targets = append(targets, dev)
+	}
+
+	for _, name := range names {
+		dev, err := api.MatchDevice(all, name)
+		if err != nil {
+			results = append(results, deviceResult{target: name, err: err})
+			continue
+		}
+		add(name, dev)
+	}
+	if allOnline {
+		for i := range all {
+			// Multi-room music groups are listed as devices but can't speak
+			if all[i].Online && all[i].DeviceFamily != "WHA" {
+				add(all[i].AccountName, &all[i])
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		if len(results) == 0 {
+			return nil, fmt.Errorf("%w: no online devices on this account", api.ErrDeviceNotFound)
+		}
+		return results, nil
+	}
+
+	// The sequence is one request, so a failure applies to every device in it
+	if err := client.RunSequence(ctx, build(targets)); errors.Is(err, api.ErrDryRun) {
+		return nil, err
+	} else if err != nil {
+		for i := range results {
+			if results[i].err == nil {
+				results[i].err = err
+			}
+		}
+	}
+	return results, nil
+}
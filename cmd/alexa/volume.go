@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newVolumeCmd(flags *rootFlags) *cobra.Command {
+	var device string
+
+	cmd := &cobra.Command{
+		Use:   "volume [level|+N|-N]",
+		Short: "Show or set the volume of a device",
+		Long: `Show or set the speaker volume of an Alexa device (0-100).
+
+With no argument the current volume is shown. A plain number sets the
+volume; +N and -N change it relative to the current level. Put -- before
+a negative step so it isn't read as a flag.
+
+Without -d, the default_device from the config file is used.
+
+Examples:
+  alexacli volume -d Kitchen
+  alexacli volume -d Kitchen 30
+  alexacli volume -d Kitchen +10
+  alexacli volume -d Kitchen -- -10`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := getFormatter(flags)
+
+			client, dev, err := deviceClient(ctx, flags, device)
+			if err != nil {
+				return err
+			}
+
+			current, err := client.GetVolume(ctx, dev)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				if flags.asJSON {
+					return out.Data(map[string]interface{}{
+						"device": dev.AccountName,
+						"volume": current.SpeakerVolume,
+						"muted":  current.SpeakerMuted,
+					})
+				}
+				muted := ""
+				if current.SpeakerMuted {
+					muted = " (muted)"
+				}
+				fmt.Printf("%s: %d%s\n", dev.AccountName, current.SpeakerVolume, muted)
+				return nil
+			}
+
+			level, err := parseVolume(args[0], current.SpeakerVolume)
+			if err != nil {
+				return err
+			}
+
+			if err := client.SetVolume(ctx, dev, level); err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.Data(map[string]interface{}{
+					"device":   dev.AccountName,
+					"volume":   level,
+					"previous": current.SpeakerVolume,
+				})
+			}
+			return out.Success(fmt.Sprintf("Volume on %s set to %d (was %d)", dev.AccountName, level, current.SpeakerVolume))
+		},
+	}
+
+	cmd.Flags().StringVarP(&device, "device", "d", "", "Device name or serial (default from config)")
+
+	return cmd
+}
+
+// parseVolume turns "30", "+10" or "-10" into an absolute level, clamping
+// relative changes to 0-100
+func parseVolume(arg string, current int) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid volume %q: expected 0-100, +N or -N", arg)
+	}
+
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		return min(max(current+n, 0), 100), nil
+	}
+	if n > 100 {
+		return 0, fmt.Errorf("invalid volume %q: expected 0-100, +N or -N", arg)
+	}
+	return n, nil
+}
@@ -2,63 +2,166 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Client is the Alexa API client
+// Client is the Alexa API client. It is safe for concurrent use.
 type Client struct {
-	httpClient     *http.Client
-	cookies        string
-	csrf           string
-	activityCSRF   string // separate CSRF for activity/history endpoints
-	amazonDomain   string // e.g., "amazon.com"
-	customerID     string
-	bearerToken    string // Atna| token for AVS APIs
-	conversationID string // Current conversation ID for Alexa+
-	refreshToken   string // Store for re-auth
-	verbose        bool   // Enable debug logging
+	httpClient   *http.Client
+	amazonDomain string // e.g., "amazon.com"
+	refreshToken string // Store for re-auth
+
+	// mu guards the credentials and customerID below, which change as the
+	// client logs in again. Network calls are never made while holding it.
+	mu            sync.Mutex
+	cookies       string
+	csrf          string
+	activityCSRF  string // separate CSRF for activity/history endpoints
+	customerID    string
+	bearerToken   string    // Atna| token for AVS APIs
+	bearerExpiry  time.Time // When bearerToken stops being valid
+	sessionExpiry time.Time // When cached cookies should be refreshed
+	cookieGen     int       // Bumped whenever cookies are replaced
+	bearerGen     int       // Bumped whenever bearerToken is replaced
+
+	// authMu serialises logins so that concurrent requests failing with the
+	// same expired credentials trigger a single re-authentication
+	authMu sync.Mutex
+
+	logger      *slog.Logger // Debug output, with credentials redacted
+	secrets     *secretSet   // Credentials to scrub from logs
+	sessionPath string       // Where authenticated state is cached (empty disables caching)
+	endpoints   Endpoints    // Base URLs for every host the client talks to
+	retry       RetryPolicy  // How transient failures are retried
+	limiter     *tokenBucket // Client-side rate limit (nil disables)
+	dryRun      func(PlannedRequest)
 }
 
-// SetVerbose enables or disables verbose debug output
-func (c *Client) SetVerbose(v bool) {
-	c.verbose = v
+// Options configures optional Client behaviour
+type Options struct {
+	// SessionPath is a file used to cache cookies and tokens between runs.
+	// When empty, every Client authenticates from scratch.
+	SessionPath string
+
+	// Logger receives structured debug output. Cookies, CSRF values and
+	// tokens are redacted before reaching it. When nil, nothing is logged.
+	Logger *slog.Logger
+
+	// Retry controls retries of network errors, 429 and 5xx responses.
+	// When nil, DefaultRetryPolicy is used.
+	Retry *RetryPolicy
+
+	// RateLimit caps outgoing requests across the whole client.
+	// When nil, DefaultRateLimit is used.
+	RateLimit *RateLimit
+
+	// Endpoints overrides the hosts the client talks to, e.g. to point it at a
+	// local stand-in. Empty fields use DefaultEndpoints for the Amazon domain.
+	Endpoints Endpoints
+
+	// RecordPath, when set, writes every HTTP exchange to a HAR file with
+	// cookies, CSRF tokens, bearer tokens and the refresh token redacted
+	RecordPath string
+
+	// ReplayPath, when set, answers requests from a HAR file written with
+	// RecordPath instead of the network
+	ReplayPath string
+
+	// DryRun, when set, receives every request that would change something on
+	// the account (sequences, smart home control, player commands and AVS
+	// events) instead of it being sent; the method then returns ErrDryRun.
+	// Read-only lookups such as GetDevices are still made.
+	DryRun func(PlannedRequest)
 }
 
-func (c *Client) log(format string, args ...interface{}) {
-	if c.verbose {
-		fmt.Printf("[DEBUG] "+format+"\n", args...)
-	}
+// NewClient creates a new Alexa API client
+func NewClient(ctx context.Context, refreshToken, amazonDomain string) (*Client, error) {
+	return NewClientWithOptions(ctx, refreshToken, amazonDomain, Options{})
 }
 
-// NewClient creates a new Alexa API client
-func NewClient(refreshToken, amazonDomain string) (*Client, error) {
+// NewClientWithOptions creates a new Alexa API client, reusing a cached session when available
+func NewClientWithOptions(ctx context.Context, refreshToken, amazonDomain string, opts Options) (*Client, error) {
 	client := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 		amazonDomain: amazonDomain,
 		refreshToken: refreshToken,
+		secrets:      &secretSet{},
+		sessionPath:  opts.SessionPath,
+		endpoints:    opts.Endpoints.withDefaults(amazonDomain),
+		retry:        DefaultRetryPolicy,
+		limiter:      newTokenBucket(DefaultRateLimit),
+		dryRun:       opts.DryRun,
+	}
+	client.logger = newLogger(opts.Logger, client.secrets)
+	client.secrets.add(refreshToken)
+	if opts.Retry != nil {
+		client.retry = *opts.Retry
+	}
+	if opts.RateLimit != nil {
+		client.limiter = newTokenBucket(*opts.RateLimit)
+	}
+
+	switch {
+	case opts.RecordPath != "" && opts.ReplayPath != "":
+		return nil, fmt.Errorf("cannot record and replay at the same time")
+	case opts.ReplayPath != "":
+		transport, err := newReplayTransport(opts.ReplayPath)
+		if err != nil {
+			return nil, err
+		}
+		client.httpClient.Transport = transport
+	case opts.RecordPath != "":
+		transport, err := newRecordingTransport(http.DefaultTransport, opts.RecordPath, client.logger, refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		client.httpClient.Transport = transport
+	}
+
+	// Hold the session lock while authenticating so concurrent invocations
+	// wait for this login instead of each starting their own
+	if client.sessionPath != "" {
+		unlock, err := lockSession(ctx, client.sessionPath)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		if client.loadSession() {
+			return client, nil
+		}
 	}
 
 	// Exchange refresh token for cookies
-	if err := client.authenticate(refreshToken); err != nil {
+	if err := client.authenticate(ctx, refreshToken); err != nil {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
+	if client.sessionPath != "" {
+		if err := client.writeSession(); err != nil {
+			client.logger.Debug("failed to save session", "error", err)
+		}
+	}
+
 	return client, nil
 }
 
 // authenticate exchanges a refresh token for session cookies
-func (c *Client) authenticate(refreshToken string) error {
+func (c *Client) authenticate(ctx context.Context, refreshToken string) error {
 	// Amazon token exchange endpoint
-	authURL := "https://api.amazon.com/ap/exchangetoken/cookies"
+	authURL := c.endpoints.Auth + "/ap/exchangetoken/cookies"
 
 	data := url.Values{}
 	data.Set("app_name", "Amazon Alexa")
@@ -67,7 +170,7 @@ func (c *Client) authenticate(refreshToken string) error {
 	data.Set("source_token", refreshToken)
 	data.Set("domain", "."+c.amazonDomain)
 
-	req, err := http.NewRequest("POST", authURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return err
 	}
@@ -75,7 +178,7 @@ func (c *Client) authenticate(refreshToken string) error {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("x-amzn-identity-auth-domain", "api."+c.amazonDomain)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("token exchange request failed: %w", err)
 	}
@@ -83,7 +186,7 @@ func (c *Client) authenticate(refreshToken string) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("token exchange failed: %w", newAuthError(resp, body))
 	}
 
 	var result struct {
@@ -109,153 +212,129 @@ func (c *Client) authenticate(refreshToken string) error {
 			cookieParts = append(cookieParts, fmt.Sprintf("%s=%s", cookie.Name, cookie.Value))
 		}
 	}
-	c.cookies = strings.Join(cookieParts, "; ")
+	cookies := strings.Join(cookieParts, "; ")
+	c.secrets.addCookies(cookies)
 
-	if c.cookies == "" {
+	if cookies == "" {
 		return fmt.Errorf("no cookies received from token exchange")
 	}
 
 	// Get CSRF token
-	if err := c.fetchCSRF(); err != nil {
+	cookies, csrf, err := c.fetchCSRF(ctx, cookies)
+	if err != nil {
 		return fmt.Errorf("failed to get CSRF token: %w", err)
 	}
 
+	// Swap in the new credentials together so no request sees a mix of old and new
+	c.mu.Lock()
+	c.cookies = cookies
+	c.csrf = csrf
+	c.activityCSRF = ""
+	c.sessionExpiry = time.Now().Add(sessionTTL)
+	c.cookieGen++
+	c.mu.Unlock()
+
 	return nil
 }
 
-// fetchCSRF retrieves the CSRF token from Amazon
-func (c *Client) fetchCSRF() error {
+// fetchCSRF retrieves the CSRF token for a set of session cookies.
+// It returns the cookies with csrf added, and the token itself.
+func (c *Client) fetchCSRF(ctx context.Context, cookies string) (string, string, error) {
 	// Use the language API endpoint which returns CSRF as a cookie
-	csrfURL := fmt.Sprintf("https://alexa.%s/api/language", c.amazonDomain)
+	csrfURL := c.alexaURL() + "/api/language"
 
-	req, err := http.NewRequest("GET", csrfURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", csrfURL, nil)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	req.Header.Set("Cookie", c.cookies)
+	req.Header.Set("Cookie", cookies)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 	defer resp.Body.Close()
 
 	// Extract csrf from response cookies
 	for _, cookie := range resp.Cookies() {
 		if cookie.Name == "csrf" {
-			c.csrf = cookie.Value
+			c.secrets.add(cookie.Value)
 			// Also add csrf to our cookie string for future requests
-			c.cookies = c.cookies + "; csrf=" + cookie.Value
-			return nil
+			return cookies + "; csrf=" + cookie.Value, cookie.Value, nil
 		}
 	}
 
 	// Try to extract from existing cookies (may already be present)
-	for _, part := range strings.Split(c.cookies, "; ") {
+	for _, part := range strings.Split(cookies, "; ") {
 		if strings.HasPrefix(part, "csrf=") {
-			c.csrf = strings.TrimPrefix(part, "csrf=")
-			return nil
+			csrf := strings.TrimPrefix(part, "csrf=")
+			c.secrets.add(csrf)
+			return cookies, csrf, nil
 		}
 	}
 
-	return fmt.Errorf("CSRF token not found")
+	return "", "", fmt.Errorf("CSRF token not found")
 }
 
-// baseURL returns the Alexa API base URL
+// baseURL returns the Alexa API base URL (pitangui/layla)
 func (c *Client) baseURL() string {
-	// pitangui for US, layla for EU/UK
-	// The subdomain changes based on region, but the TLD matches the user's amazon domain
-	switch c.amazonDomain {
-	case "amazon.com":
-		return "https://pitangui.amazon.com"
-	case "amazon.co.uk":
-		return "https://layla.amazon.co.uk"
-	case "amazon.de":
-		return "https://layla.amazon.de"
-	case "amazon.fr":
-		return "https://layla.amazon.fr"
-	case "amazon.it":
-		return "https://layla.amazon.it"
-	case "amazon.es":
-		return "https://layla.amazon.es"
-	case "amazon.co.jp":
-		return "https://layla.amazon.co.jp"
-	case "amazon.com.au":
-		return "https://alexa.amazon.com.au"
-	case "amazon.ca":
-		return "https://pitangui.amazon.ca"
-	case "amazon.com.br":
-		return "https://pitangui.amazon.com.br"
-	case "amazon.in":
-		return "https://pitangui.amazon.in"
-	default:
-		// Fall back to layla with the user's domain
-		return fmt.Sprintf("https://layla.%s", c.amazonDomain)
-	}
+	return c.endpoints.API
 }
 
 // alexaURL returns the alexa.amazon.com base URL
 func (c *Client) alexaURL() string {
-	return fmt.Sprintf("https://alexa.%s", c.amazonDomain)
+	return c.endpoints.Alexa
 }
 
 // request makes an authenticated request to the Alexa API (pitangui/layla)
-func (c *Client) request(method, endpoint string, body interface{}) ([]byte, error) {
-	return c.doRequest(c.baseURL(), method, endpoint, body)
+func (c *Client) request(ctx context.Context, method, endpoint string, body interface{}) ([]byte, error) {
+	return c.doRequest(ctx, c.baseURL(), method, endpoint, body)
 }
 
 // requestAlexa makes an authenticated request to alexa.amazon.com
-func (c *Client) requestAlexa(method, endpoint string, body interface{}) ([]byte, error) {
-	return c.doRequest(c.alexaURL(), method, endpoint, body)
+func (c *Client) requestAlexa(ctx context.Context, method, endpoint string, body interface{}) ([]byte, error) {
+	return c.doRequest(ctx, c.alexaURL(), method, endpoint, body)
 }
 
 // doRequest makes an authenticated request to the specified base URL
-func (c *Client) doRequest(baseURL, method, endpoint string, body interface{}) ([]byte, error) {
-	var reqBody io.Reader
-	var jsonBodyStr string
+func (c *Client) doRequest(ctx context.Context, baseURL, method, endpoint string, body interface{}) ([]byte, error) {
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
-		jsonBodyStr = string(jsonBody)
-		reqBody = bytes.NewReader(jsonBody)
 	}
 
 	fullURL := baseURL + endpoint
-	c.log("Request: %s %s", method, fullURL)
-	if jsonBodyStr != "" {
-		c.log("Request body: %s", jsonBodyStr[:min(500, len(jsonBodyStr))])
-	}
-
-	req, err := http.NewRequest(method, fullURL, reqBody)
-	if err != nil {
-		return nil, err
-	}
 
-	req.Header.Set("Cookie", c.cookies)
-	req.Header.Set("csrf", c.csrf)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	resp, respBody, err := c.send(ctx, authCookies, func() (*http.Request, error) {
+		var reqBody io.Reader
+		if jsonBody != nil {
+			reqBody = bytes.NewReader(jsonBody)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		c.log("Request error: %v", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+		if err != nil {
+			return nil, err
+		}
 
-	respBody, err := io.ReadAll(resp.Body)
+		creds := c.creds()
+		req.Header.Set("Cookie", creds.cookies)
+		req.Header.Set("csrf", creds.csrf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.log("Response status: %d, body length: %d", resp.StatusCode, len(respBody))
 	if resp.StatusCode >= 400 {
-		c.log("Error response: %s", string(respBody))
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, newAPIError(resp, respBody)
 	}
 
 	return respBody, nil
@@ -263,18 +342,23 @@ func (c *Client) doRequest(baseURL, method, endpoint string, body interface{}) (
 
 // Device represents an Alexa device
 type Device struct {
-	AccountName               string `json:"accountName"`
-	SerialNumber              string `json:"serialNumber"`
-	DeviceType                string `json:"deviceType"`
-	DeviceFamily              string `json:"deviceFamily"`
-	DeviceOwnerCustomerID     string `json:"deviceOwnerCustomerId"`
-	Online                    bool   `json:"online"`
-	Capabilities              []string `json:"capabilities"`
+	AccountName           string   `json:"accountName"`
+	SerialNumber          string   `json:"serialNumber"`
+	DeviceType            string   `json:"deviceType"`
+	DeviceFamily          string   `json:"deviceFamily"`
+	DeviceOwnerCustomerID string   `json:"deviceOwnerCustomerId"`
+	Online                bool     `json:"online"`
+	Capabilities          []string `json:"capabilities"`
+}
+
+// Logger returns the client's debug logger, which redacts credentials like the client's own logs
+func (c *Client) Logger() *slog.Logger {
+	return c.logger
 }
 
 // GetDevices returns all Alexa devices
-func (c *Client) GetDevices() ([]Device, error) {
-	data, err := c.request("GET", "/api/devices-v2/device?cached=true", nil)
+func (c *Client) GetDevices(ctx context.Context) ([]Device, error) {
+	data, err := c.request(ctx, "GET", "/api/devices-v2/device?cached=true", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -288,187 +372,212 @@ func (c *Client) GetDevices() ([]Device, error) {
 
 	// Store customer ID from first device
 	if len(result.Devices) > 0 {
+		c.mu.Lock()
+		changed := c.customerID != result.Devices[0].DeviceOwnerCustomerID
 		c.customerID = result.Devices[0].DeviceOwnerCustomerID
+		c.mu.Unlock()
+
+		if changed {
+			c.saveSession(ctx)
+		}
 	}
 
 	return result.Devices, nil
 }
 
-// SequenceCommand sends a sequence command to a device
-func (c *Client) SequenceCommand(device *Device, command string) error {
-	// Ensure we have customer ID
-	if c.customerID == "" {
-		c.customerID = device.DeviceOwnerCustomerID
+// FindDevice finds a device by serial number or name.
+// Exact matches win; otherwise a case-insensitive partial name match must be unique.
+func (c *Client) FindDevice(ctx context.Context, nameOrSerial string) (*Device, error) {
+	devices, err := c.GetDevices(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return MatchDevice(devices, nameOrSerial)
+}
 
-	// Parse command type and build the appropriate payload
-	var sequenceJson string
+// MatchDevice picks a device from a list by serial number or name, using the same rules as FindDevice
+func MatchDevice(devices []Device, nameOrSerial string) (*Device, error) {
+	for i, d := range devices {
+		if d.SerialNumber == nameOrSerial {
+			return &devices[i], nil
+		}
+	}
 
-	switch {
-	case strings.HasPrefix(command, "speak:"):
-		text := strings.TrimPrefix(command, "speak:")
-		text = strings.Trim(text, "'\"")
-
-		sequenceJson = fmt.Sprintf(`{
-			"@type": "com.amazon.alexa.behaviors.model.Sequence",
-			"startNode": {
-				"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
-				"type": "Alexa.Speak",
-				"operationPayload": {
-					"deviceType": "%s",
-					"deviceSerialNumber": "%s",
-					"customerId": "%s",
-					"locale": "%s",
-					"textToSpeak": %s
-				}
-			}
-		}`, device.DeviceType, device.SerialNumber, c.customerID, c.locale(), mustJSON(text))
-
-	case strings.HasPrefix(command, "announcement:"):
-		text := strings.TrimPrefix(command, "announcement:")
-		text = strings.Trim(text, "'\"")
-
-		sequenceJson = fmt.Sprintf(`{
-			"@type": "com.amazon.alexa.behaviors.model.Sequence",
-			"startNode": {
-				"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
-				"type": "AlexaAnnouncement",
-				"operationPayload": {
-					"expireAfter": "PT5S",
-					"content": [{
-						"locale": "%s",
-						"display": {"title": "Announcement", "body": %s},
-						"speak": {"type": "text", "value": %s}
-					}],
-					"target": {
-						"customerId": "%s"
-					}
-				}
-			}
-		}`, c.locale(), mustJSON(text), mustJSON(text), c.customerID)
-
-	case strings.HasPrefix(command, "textcommand:"):
-		text := strings.TrimPrefix(command, "textcommand:")
-		text = strings.Trim(text, "'\"")
-
-		sequenceJson = fmt.Sprintf(`{
-			"@type": "com.amazon.alexa.behaviors.model.Sequence",
-			"startNode": {
-				"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
-				"type": "Alexa.TextCommand",
-				"skillId": "amzn1.ask.1p.tellalexa",
-				"operationPayload": {
-					"deviceType": "%s",
-					"deviceSerialNumber": "%s",
-					"customerId": "%s",
-					"locale": "%s",
-					"text": %s
-				}
-			}
-		}`, device.DeviceType, device.SerialNumber, c.customerID, c.locale(), mustJSON(text))
+	names := make([]string, len(devices))
+	for i, d := range devices {
+		names[i] = d.AccountName
+	}
 
-	case strings.HasPrefix(command, "automation:"):
-		routineName := strings.TrimPrefix(command, "automation:")
-		routineName = strings.Trim(routineName, "'\"")
-		return c.ExecuteRoutine(device, routineName)
+	i, err := matchName(nameOrSerial, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", err, nameOrSerial)
+	}
+	return &devices[i], nil
+}
 
+// matchName returns the index of the name matching query: an exact match,
+// then a case-insensitive one, then a unique case-insensitive substring
+func matchName(query string, names []string) (int, error) {
+	for i, n := range names {
+		if n == query {
+			return i, nil
+		}
+	}
+
+	queryLower := strings.ToLower(query)
+	for i, n := range names {
+		if strings.ToLower(n) == queryLower {
+			return i, nil
+		}
+	}
+
+	var matches []int
+	for i, n := range names {
+		if strings.Contains(strings.ToLower(n), queryLower) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, ErrDeviceNotFound
+	case 1:
+		return matches[0], nil
 	default:
-		return fmt.Errorf("unknown command type: %s", command)
+		candidates := make([]string, len(matches))
+		for j, i := range matches {
+			candidates[j] = fmt.Sprintf("%q", names[i])
+		}
+		return -1, fmt.Errorf("%w between %s", ErrAmbiguousMatch, strings.Join(candidates, ", "))
 	}
+}
+
+// customerIDFor returns the account's customer ID, learning it from device if not yet known
+func (c *Client) customerIDFor(device *Device) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.customerID == "" {
+		c.customerID = device.DeviceOwnerCustomerID
+	}
+	return c.customerID
+}
 
-	payload := map[string]interface{}{
-		"behaviorId":   "PREVIEW",
-		"sequenceJson": sequenceJson,
-		"status":       "ENABLED",
+// SequenceCommand sends a sequence command to a device.
+// command is a step as accepted by ParseStep, or "automation:" followed by a routine name.
+func (c *Client) SequenceCommand(ctx context.Context, device *Device, command string) error {
+	// Routines replay their own sequence rather than building one
+	if routineName, ok := strings.CutPrefix(command, "automation:"); ok {
+		return c.ExecuteRoutine(ctx, device, strings.Trim(routineName, "'\""))
 	}
 
-	_, err := c.request("POST", "/api/behaviors/preview", payload)
-	return err
+	node, err := c.ParseStep(device, command)
+	if err != nil {
+		return err
+	}
+	return c.RunSequence(ctx, NewSequence(node))
 }
 
 // ExecuteRoutine runs an Alexa routine by name
-func (c *Client) ExecuteRoutine(device *Device, routineName string) error {
+func (c *Client) ExecuteRoutine(ctx context.Context, device *Device, routineName string) error {
 	// First, get the list of routines
-	routines, err := c.GetRoutines()
+	routines, err := c.GetRoutines(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get routines: %w", err)
 	}
 
-	// Find matching routine
-	var targetRoutine *Routine
-	nameLower := strings.ToLower(routineName)
-	for i, r := range routines {
-		if strings.ToLower(r.Name) == nameLower {
-			targetRoutine = &routines[i]
-			break
-		}
-	}
-
-	if targetRoutine == nil {
-		return fmt.Errorf("routine '%s' not found", routineName)
+	targetRoutine, err := MatchRoutine(routines, routineName)
+	if err != nil {
+		return err
 	}
 
 	// Execute the routine
-	payload := map[string]interface{}{
-		"behaviorId": targetRoutine.AutomationID,
-		"sequenceJson": targetRoutine.Sequence,
-		"status": "ENABLED",
-	}
-
-	_, err = c.request("POST", "/api/behaviors/preview", payload)
-	return err
+	return c.runBehavior(ctx, targetRoutine.AutomationID, targetRoutine.Sequence)
 }
 
 // Routine represents an Alexa routine
 type Routine struct {
-	AutomationID string `json:"automationId"`
-	Name         string `json:"name"`
-	Sequence     string `json:"sequence"`
+	AutomationID string          `json:"automationId"`
+	Name         string          `json:"name"`
+	Status       string          `json:"status,omitempty"`
+	Sequence     string          `json:"sequence"`
+	Triggers     json.RawMessage `json:"triggers,omitempty"` // as returned by Amazon; see ParseRoutine
+
+	// Raw is the whole automation as Amazon returned it, including fields not
+	// modelled above. Saving the routine sends it back with those fields updated.
+	Raw json.RawMessage `json:"-"`
 }
 
 // GetRoutines returns all Alexa routines
-func (c *Client) GetRoutines() ([]Routine, error) {
+func (c *Client) GetRoutines(ctx context.Context) ([]Routine, error) {
 	// Routines are on alexa.amazon.com, not pitangui
-	data, err := c.requestAlexa("GET", "/api/behaviors/automations", nil)
+	data, err := c.requestAlexa(ctx, "GET", "/api/behaviors/automations", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var rawRoutines []struct {
-		AutomationID string `json:"automationId"`
-		Name         string `json:"name"`
-		Sequence     json.RawMessage `json:"sequence"`
-	}
-
+	var rawRoutines []json.RawMessage
 	if err := json.Unmarshal(data, &rawRoutines); err != nil {
 		return nil, fmt.Errorf("failed to parse routines: %w", err)
 	}
 
 	routines := make([]Routine, len(rawRoutines))
-	for i, r := range rawRoutines {
-		routines[i] = Routine{
-			AutomationID: r.AutomationID,
-			Name:         r.Name,
-			Sequence:     string(r.Sequence),
+	for i, raw := range rawRoutines {
+		r, err := decodeAutomation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse routines: %w", err)
 		}
+		routines[i] = *r
 	}
 
 	return routines, nil
 }
 
+// decodeAutomation reads a routine from an automation as Amazon stores it
+func decodeAutomation(raw json.RawMessage) (*Routine, error) {
+	var a struct {
+		AutomationID string          `json:"automationId"`
+		Name         string          `json:"name"`
+		Status       string          `json:"status"`
+		Sequence     json.RawMessage `json:"sequence"`
+		Triggers     json.RawMessage `json:"triggers"`
+	}
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+
+	return &Routine{
+		AutomationID: a.AutomationID,
+		Name:         a.Name,
+		Status:       a.Status,
+		Sequence:     string(a.Sequence),
+		Triggers:     a.Triggers,
+		Raw:          raw,
+	}, nil
+}
+
+// MatchRoutine finds a routine by name, ignoring case
+func MatchRoutine(routines []Routine, name string) (*Routine, error) {
+	for i, r := range routines {
+		if strings.EqualFold(r.Name, name) {
+			return &routines[i], nil
+		}
+	}
+	return nil, fmt.Errorf("routine '%s' not found", name)
+}
+
 // SmartHomeDevice represents a smart home device
 type SmartHomeDevice struct {
-	EntityID     string `json:"entityId"`
-	ApplianceID  string `json:"applianceId"`
-	Name         string `json:"friendlyName"`
-	Description  string `json:"friendlyDescription"`
-	Type         string `json:"applianceTypes"`
-	Reachable    bool   `json:"isReachable"`
+	EntityID    string `json:"entityId"`
+	ApplianceID string `json:"applianceId"`
+	Name        string `json:"friendlyName"`
+	Description string `json:"friendlyDescription"`
+	Type        string `json:"applianceTypes"`
+	Reachable   bool   `json:"isReachable"`
 }
 
 // GetSmartHomeDevices returns all smart home devices
-func (c *Client) GetSmartHomeDevices() ([]SmartHomeDevice, error) {
-	data, err := c.request("GET", "/api/phoenix", nil)
+func (c *Client) GetSmartHomeDevices(ctx context.Context) ([]SmartHomeDevice, error) {
+	data, err := c.request(ctx, "GET", "/api/phoenix", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -494,8 +603,27 @@ func (c *Client) GetSmartHomeDevices() ([]SmartHomeDevice, error) {
 	return devices, nil
 }
 
+// FindSmartHomeDevice finds a smart home device by name, using the same matching rules as FindDevice
+func (c *Client) FindSmartHomeDevice(ctx context.Context, name string) (*SmartHomeDevice, error) {
+	devices, err := c.GetSmartHomeDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(devices))
+	for i, d := range devices {
+		names[i] = d.Name
+	}
+
+	i, err := matchName(name, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w: smart home device '%s'", err, name)
+	}
+	return &devices[i], nil
+}
+
 // ControlSmartHome controls a smart home device
-func (c *Client) ControlSmartHome(entityID string, action string, value interface{}) error {
+func (c *Client) ControlSmartHome(ctx context.Context, entityID string, action string, value interface{}) error {
 	var payload map[string]interface{}
 
 	switch action {
@@ -503,7 +631,7 @@ func (c *Client) ControlSmartHome(entityID string, action string, value interfac
 		payload = map[string]interface{}{
 			"controlRequests": []map[string]interface{}{
 				{
-					"entityId":  entityID,
+					"entityId":   entityID,
 					"entityType": "APPLIANCE",
 					"parameters": map[string]interface{}{
 						"action": "turnOn",
@@ -515,7 +643,7 @@ func (c *Client) ControlSmartHome(entityID string, action string, value interfac
 		payload = map[string]interface{}{
 			"controlRequests": []map[string]interface{}{
 				{
-					"entityId":  entityID,
+					"entityId":   entityID,
 					"entityType": "APPLIANCE",
 					"parameters": map[string]interface{}{
 						"action": "turnOff",
@@ -527,10 +655,10 @@ func (c *Client) ControlSmartHome(entityID string, action string, value interfac
 		payload = map[string]interface{}{
 			"controlRequests": []map[string]interface{}{
 				{
-					"entityId":  entityID,
+					"entityId":   entityID,
 					"entityType": "APPLIANCE",
 					"parameters": map[string]interface{}{
-						"action": "setBrightness",
+						"action":     "setBrightness",
 						"brightness": value,
 					},
 				},
@@ -540,30 +668,29 @@ func (c *Client) ControlSmartHome(entityID string, action string, value interfac
 		return fmt.Errorf("unknown action: %s", action)
 	}
 
-	_, err := c.request("PUT", "/api/phoenix/state", payload)
+	if err := c.checkDryRun(newPlannedRequest("PUT", c.baseURL()+"/api/phoenix/state", payload)); err != nil {
+		return err
+	}
+
+	_, err := c.request(ctx, "PUT", "/api/phoenix/state", payload)
 	return err
 }
 
 // fetchActivityCSRF retrieves the CSRF token needed for activity/history endpoints
-func (c *Client) fetchActivityCSRF() error {
-	activityURL := fmt.Sprintf("https://www.%s/alexa-privacy/apd/activity?ref=activityHistory", c.amazonDomain)
+func (c *Client) fetchActivityCSRF(ctx context.Context) error {
+	activityURL := c.endpoints.Activity + "/alexa-privacy/apd/activity?ref=activityHistory"
 
-	req, err := http.NewRequest("GET", activityURL, nil)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Cookie", c.cookies)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	_, body, err := c.send(ctx, authCookies, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", activityURL, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	body, err := io.ReadAll(resp.Body)
+		req.Header.Set("Cookie", c.creds().cookies)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
+		return req, nil
+	})
 	if err != nil {
 		return err
 	}
@@ -575,7 +702,7 @@ func (c *Client) fetchActivityCSRF() error {
 	re := regexp.MustCompile(`<meta name="csrf-token" content="([^"]+)"`)
 	matches := re.FindStringSubmatch(bodyStr)
 	if len(matches) >= 2 {
-		c.activityCSRF = matches[1]
+		c.setActivityCSRF(ctx, matches[1])
 		return nil
 	}
 
@@ -583,7 +710,7 @@ func (c *Client) fetchActivityCSRF() error {
 	re2 := regexp.MustCompile(`data-csrf="([^"]+)"`)
 	matches = re2.FindStringSubmatch(bodyStr)
 	if len(matches) >= 2 {
-		c.activityCSRF = matches[1]
+		c.setActivityCSRF(ctx, matches[1])
 		return nil
 	}
 
@@ -591,7 +718,7 @@ func (c *Client) fetchActivityCSRF() error {
 	re3 := regexp.MustCompile(`"csrfToken"\s*:\s*"([^"]+)"`)
 	matches = re3.FindStringSubmatch(bodyStr)
 	if len(matches) >= 2 {
-		c.activityCSRF = matches[1]
+		c.setActivityCSRF(ctx, matches[1])
 		return nil
 	}
 
@@ -599,73 +726,78 @@ func (c *Client) fetchActivityCSRF() error {
 	re4 := regexp.MustCompile(`anti-csrftoken-a2z['":\s]+['"]([^'"]+)['"]`)
 	matches = re4.FindStringSubmatch(bodyStr)
 	if len(matches) >= 2 {
-		c.activityCSRF = matches[1]
+		c.setActivityCSRF(ctx, matches[1])
 		return nil
 	}
 
 	return fmt.Errorf("activity CSRF token not found in page")
 }
 
+// setActivityCSRF stores a freshly scraped activity CSRF token and caches it
+func (c *Client) setActivityCSRF(ctx context.Context, token string) {
+	c.secrets.add(token)
+	c.mu.Lock()
+	c.activityCSRF = token
+	c.mu.Unlock()
+	c.saveSession(ctx)
+}
+
 // HistoryRecord represents a voice history record
 type HistoryRecord struct {
-	RecordKey       string    `json:"recordKey"`
-	Timestamp       int64     `json:"timestamp"`
-	Device          string    `json:"device"`
-	CustomerUtterance string  `json:"customerUtterance"` // What you said (ASR)
-	AlexaResponse   string    `json:"alexaResponse"`     // What Alexa said (TTS)
+	RecordKey         string `json:"recordKey"`
+	Timestamp         int64  `json:"timestamp"`
+	Device            string `json:"device"`
+	CustomerUtterance string `json:"customerUtterance"` // What you said (ASR)
+	AlexaResponse     string `json:"alexaResponse"`     // What Alexa said (TTS)
 }
 
 // GetCustomerHistoryRecords retrieves recent voice activity history
-func (c *Client) GetCustomerHistoryRecords(startTime, endTime int64) ([]HistoryRecord, error) {
+func (c *Client) GetCustomerHistoryRecords(ctx context.Context, startTime, endTime int64) ([]HistoryRecord, error) {
 	// Ensure we have the activity CSRF token
-	if c.activityCSRF == "" {
-		if err := c.fetchActivityCSRF(); err != nil {
+	if c.creds().activityCSRF == "" {
+		if err := c.fetchActivityCSRF(ctx); err != nil {
 			return nil, fmt.Errorf("failed to get activity CSRF: %w", err)
 		}
 	}
 
 	// Build URL with time range
 	historyURL := fmt.Sprintf(
-		"https://www.%s/alexa-privacy/apd/rvh/customer-history-records-v2/?startTime=%d&endTime=%d&pageType=VOICE_HISTORY",
-		c.amazonDomain, startTime, endTime,
+		"%s/alexa-privacy/apd/rvh/customer-history-records-v2/?startTime=%d&endTime=%d&pageType=VOICE_HISTORY",
+		c.endpoints.Activity, startTime, endTime,
 	)
 
-	body := bytes.NewReader([]byte(`{"previousRequestToken": null}`))
-	req, err := http.NewRequest("POST", historyURL, body)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Cookie", c.cookies)
-	req.Header.Set("csrf", c.csrf)
-	req.Header.Set("anti-csrftoken-a2z", c.activityCSRF)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Origin", fmt.Sprintf("https://www.%s", c.amazonDomain))
-	req.Header.Set("Referer", fmt.Sprintf("https://www.%s/alexa-privacy/apd/activity?ref=activityHistory", c.amazonDomain))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	resp, respBody, err := c.send(ctx, authActivity, func() (*http.Request, error) {
+		body := bytes.NewReader([]byte(`{"previousRequestToken": null}`))
+		req, err := http.NewRequestWithContext(ctx, "POST", historyURL, body)
+		if err != nil {
+			return nil, err
+		}
 
-	respBody, err := io.ReadAll(resp.Body)
+		creds := c.creds()
+		req.Header.Set("Cookie", creds.cookies)
+		req.Header.Set("csrf", creds.csrf)
+		req.Header.Set("anti-csrftoken-a2z", creds.activityCSRF)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/plain, */*")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("Origin", c.endpoints.Activity)
+		req.Header.Set("Referer", c.endpoints.Activity+"/alexa-privacy/apd/activity?ref=activityHistory")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("history API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("history request failed: %w", newAPIError(resp, respBody))
 	}
 
 	// Parse the response
 	var result struct {
 		CustomerHistoryRecords []struct {
-			RecordKey              string `json:"recordKey"`
-			Timestamp              int64  `json:"timestamp"`
+			RecordKey               string `json:"recordKey"`
+			Timestamp               int64  `json:"timestamp"`
 			VoiceHistoryRecordItems []struct {
 				RecordItemType string `json:"recordItemType"`
 				TranscriptText string `json:"transcriptText"`
@@ -714,12 +846,12 @@ func (c *Client) GetCustomerHistoryRecords(startTime, endTime int64) ([]HistoryR
 }
 
 // Ask sends a voice command and waits for Alexa's response
-func (c *Client) Ask(device *Device, question string, timeout time.Duration) (string, error) {
+func (c *Client) Ask(ctx context.Context, device *Device, question string, timeout time.Duration) (string, error) {
 	// Record the time before sending the command
 	startTime := time.Now().UnixMilli() - 1000 // 1 second buffer
 
 	// Send the command
-	if err := c.SequenceCommand(device, fmt.Sprintf("textcommand:'%s'", question)); err != nil {
+	if err := c.SequenceCommand(ctx, device, fmt.Sprintf("textcommand:'%s'", question)); err != nil {
 		return "", fmt.Errorf("failed to send command: %w", err)
 	}
 
@@ -728,12 +860,15 @@ func (c *Client) Ask(device *Device, question string, timeout time.Duration) (st
 	pollInterval := 500 * time.Millisecond
 
 	for time.Now().Before(endTime) {
-		time.Sleep(pollInterval)
+		if err := SleepContext(ctx, pollInterval); err != nil {
+			return "", err
+		}
 
 		// Get recent history
-		records, err := c.GetCustomerHistoryRecords(startTime, time.Now().UnixMilli()+60000)
+		records, err := c.GetCustomerHistoryRecords(ctx, startTime, time.Now().UnixMilli()+60000)
 		if err != nil {
 			// Don't fail immediately, might be a transient error
+			c.logger.Debug("history poll failed", "error", err)
 			continue
 		}
 
@@ -751,7 +886,7 @@ func (c *Client) Ask(device *Device, question string, timeout time.Duration) (st
 
 			// Check if the utterance matches our question (case-insensitive partial match)
 			if record.CustomerUtterance != "" &&
-			   strings.Contains(strings.ToLower(record.CustomerUtterance), strings.ToLower(question[:min(len(question), 20)])) {
+				strings.Contains(strings.ToLower(record.CustomerUtterance), strings.ToLower(question[:min(len(question), 20)])) {
 				if record.AlexaResponse != "" {
 					return record.AlexaResponse, nil
 				}
@@ -759,19 +894,20 @@ func (c *Client) Ask(device *Device, question string, timeout time.Duration) (st
 		}
 	}
 
-	return "", fmt.Errorf("timeout waiting for Alexa response")
+	return "", ErrTimeout
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
+// SleepContext pauses for d, returning early with the context's error if it is cancelled
+func SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
 
-func mustJSON(v interface{}) string {
-	data, _ := json.Marshal(v)
-	return string(data)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
 
 // locale returns the appropriate locale for the user's Amazon domain
@@ -810,30 +946,35 @@ func (c *Client) locale() string {
 
 // avsURL returns the AVS API base URL
 func (c *Client) avsURL() string {
-	// AVS has regional endpoints
-	switch c.amazonDomain {
-	case "amazon.com", "amazon.ca", "amazon.com.br":
-		return "https://avs-alexa-12-na.amazon.com"
-	case "amazon.co.uk", "amazon.de", "amazon.fr", "amazon.it", "amazon.es", "amazon.in":
-		return "https://avs-alexa-eu.amazon.com"
-	case "amazon.co.jp":
-		return "https://avs-alexa-fe.amazon.com"
-	default:
-		// Default to EU for unknown domains (safer for most non-US users)
-		return "https://avs-alexa-eu.amazon.com"
-	}
+	return c.endpoints.AVS
 }
 
 // getBearerToken obtains an access token for AVS APIs
-func (c *Client) getBearerToken() error {
-	if c.bearerToken != "" {
-		c.log("Using cached bearer token")
+func (c *Client) getBearerToken(ctx context.Context) error {
+	if c.hasBearerToken() {
 		return nil // Already have one
 	}
 
-	c.log("Requesting new bearer token from api.amazon.com/auth/token")
+	// Only one goroutine fetches; the rest wait and reuse its token
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.hasBearerToken() {
+		return nil
+	}
+	return c.fetchBearerToken(ctx)
+}
+
+// hasBearerToken reports whether the current bearer token is still usable
+func (c *Client) hasBearerToken() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bearerToken != "" && time.Now().Before(c.bearerExpiry)
+}
 
-	authURL := "https://api.amazon.com/auth/token"
+// fetchBearerToken exchanges the refresh token for a new bearer token.
+// The caller must hold c.authMu.
+func (c *Client) fetchBearerToken(ctx context.Context) error {
+	authURL := c.endpoints.Auth + "/auth/token"
 
 	data := url.Values{}
 	data.Set("requested_token_type", "access_token")
@@ -842,7 +983,7 @@ func (c *Client) getBearerToken() error {
 	data.Set("app_name", "Amazon Alexa")
 	data.Set("app_version", "2.2.696573.0")
 
-	req, err := http.NewRequest("POST", authURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return err
 	}
@@ -851,30 +992,42 @@ func (c *Client) getBearerToken() error {
 	req.Header.Set("x-amzn-identity-auth-domain", "api.amazon.com")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("bearer token request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
-	c.log("Bearer token response status: %d", resp.StatusCode)
-
 	if resp.StatusCode != http.StatusOK {
-		c.log("Bearer token error body: %s", string(body))
-		return fmt.Errorf("bearer token failed with status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("bearer token request failed: %w", newAuthError(resp, body))
 	}
 
 	var result struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return fmt.Errorf("failed to parse bearer token response: %w", err)
 	}
 
+	// Tokens normally last an hour; refresh a minute early to avoid racing expiry
+	lifetime := time.Duration(result.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = time.Hour
+	}
+	expiry := time.Now().Add(lifetime - time.Minute)
+	c.secrets.add(result.AccessToken)
+
+	c.mu.Lock()
 	c.bearerToken = result.AccessToken
-	c.log("Got bearer token: %s...", c.bearerToken[:min(20, len(c.bearerToken))])
+	c.bearerExpiry = expiry
+	c.bearerGen++
+	c.mu.Unlock()
+
+	c.saveSession(ctx)
+	c.logger.Debug("got bearer token", "expires", expiry)
 	return nil
 }
 
@@ -946,17 +1099,17 @@ type ConversationResponse struct {
 	Token          string                 `json:"token"`
 }
 
-// SendAVSTextMessage sends a text message via AVS (Alexa+ Type-to-Alexa)
-func (c *Client) SendAVSTextMessage(text string) error {
-	_, _, err := c.SendAVSTextMessageWithResponse(text)
+// SendAVSTextMessage sends a text message via AVS (Alexa+ Type-to-Alexa).
+// An empty conversationID lets Alexa start a new conversation.
+func (c *Client) SendAVSTextMessage(ctx context.Context, conversationID, text string) error {
+	_, _, err := c.SendAVSTextMessageWithResponse(ctx, conversationID, text)
 	return err
 }
 
-// SendAVSTextMessageWithResponse sends text and returns conversation ID and any immediate response
-func (c *Client) SendAVSTextMessageWithResponse(text string) (conversationID string, responseText string, err error) {
-	c.log("SendAVSTextMessageWithResponse: %q", text)
-
-	if err := c.getBearerToken(); err != nil {
+// SendAVSTextMessageWithResponse sends text in a conversation and returns the conversation ID
+// Alexa used and any immediate response. An empty conversationID starts a new conversation.
+func (c *Client) SendAVSTextMessageWithResponse(ctx context.Context, conversationID, text string) (string, string, error) {
+	if err := c.getBearerToken(ctx); err != nil {
 		return "", "", fmt.Errorf("failed to get bearer token: %w", err)
 	}
 
@@ -965,11 +1118,10 @@ func (c *Client) SendAVSTextMessageWithResponse(text string) (conversationID str
 	messageID := strings.ToUpper(generateUUID())
 	boundary := strings.ToUpper(generateUUID())
 
-	c.log("Dialog request ID: %s", dialogRequestID)
-	c.log("Conversation ID in context: %s", c.conversationID)
+	c.logger.Debug("sending AVS text message", "dialog_request_id", dialogRequestID, "conversation_id", conversationID)
 
 	// Build context and add the text event
-	contexts := c.buildAVSContext()
+	contexts := c.buildAVSContext(conversationID)
 
 	// Build the multipart form data
 	event := map[string]interface{}{
@@ -989,6 +1141,10 @@ func (c *Client) SendAVSTextMessageWithResponse(text string) (conversationID str
 
 	eventJSON, _ := json.Marshal(event)
 
+	if err := c.checkDryRun(PlannedRequest{Method: "POST", URL: c.avsURL() + "/v20160207/events", Body: eventJSON}); err != nil {
+		return "", "", err
+	}
+
 	// Build multipart body
 	var body bytes.Buffer
 	body.WriteString(fmt.Sprintf("--%s\r\n", boundary))
@@ -997,71 +1153,60 @@ func (c *Client) SendAVSTextMessageWithResponse(text string) (conversationID str
 	body.Write(eventJSON)
 	body.WriteString(fmt.Sprintf("\r\n--%s--", boundary))
 
-	c.log("Request body size: %d bytes (captured was 4187)", body.Len())
-
-	req, err2 := http.NewRequest("POST", c.avsURL()+"/v20160207/events", &body)
-	if err2 != nil {
-		return "", "", err2
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
-	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("User-Agent", "Alexa/2.2.696573 CFNetwork/3860.200.71 Darwin/25.1.0")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Priority", "u=1, i")
-	// Add cookies - the AVS endpoint may need session cookies in addition to bearer token
-	if c.cookies != "" {
-		req.Header.Set("Cookie", c.cookies)
-	}
+	resp, respBody, err2 := c.send(ctx, authBearer, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.avsURL()+"/v20160207/events", bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err2 := c.httpClient.Do(req)
+		creds := c.creds()
+		req.Header.Set("Authorization", "Bearer "+creds.bearerToken)
+		req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
+		req.Header.Set("Accept", "*/*")
+		req.Header.Set("User-Agent", "Alexa/2.2.696573 CFNetwork/3860.200.71 Darwin/25.1.0")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		req.Header.Set("Priority", "u=1, i")
+		// Add cookies - the AVS endpoint may need session cookies in addition to bearer token
+		if creds.cookies != "" {
+			req.Header.Set("Cookie", creds.cookies)
+		}
+		return req, nil
+	})
 	if err2 != nil {
 		return "", "", fmt.Errorf("AVS request failed: %w", err2)
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	c.log("AVS response status: %d", resp.StatusCode)
-	c.log("AVS response length: %d bytes", len(respBody))
 
 	// 204 No Content is success but no data
 	if resp.StatusCode == http.StatusNoContent {
-		c.log("Got 204 No Content - no response data")
 		return "", "", nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		c.log("AVS error response: %s", string(respBody))
-		return "", "", fmt.Errorf("AVS error %d: %s", resp.StatusCode, string(respBody))
+		return "", "", fmt.Errorf("AVS request failed: %w", newAPIError(resp, respBody))
 	}
 
 	// Parse multipart response for AddFragments directives
 	// Response format: multipart/related with JSON directives
 	respStr := string(respBody)
 
-	c.log("AVS response (first 500 chars): %s", respStr[:min(500, len(respStr))])
-
 	// Extract conversation ID from AddFragments directive
 	convIDRegex := regexp.MustCompile(`"conversationId"\s*:\s*"(amzn1\.conversation\.[^"]+)"`)
 	if matches := convIDRegex.FindStringSubmatch(respStr); len(matches) > 1 {
 		conversationID = matches[1]
-		c.log("Found conversation ID: %s", conversationID)
+		c.logger.Debug("found conversation ID", "conversation_id", conversationID)
 	}
 
 	// Look for LLM response text in fragments
+	var responseText string
 	textRegex := regexp.MustCompile(`"text"\s*:\s*"([^"]+)"`)
 	if matches := textRegex.FindAllStringSubmatch(respStr, -1); len(matches) > 0 {
-		c.log("Found %d text matches in response", len(matches))
 		// Skip the first match which is usually the user's question
 		for i, match := range matches {
 			if i > 0 && len(match) > 1 && !strings.Contains(match[1], text) {
 				// This might be an LLM response
 				if strings.Contains(respStr, "LLM:APE") || strings.Contains(respStr, `"purpose":"AGENT"`) {
 					responseText = match[1]
-					c.log("Found potential LLM response: %s", responseText)
 					break
 				}
 			}
@@ -1072,7 +1217,7 @@ func (c *Client) SendAVSTextMessageWithResponse(text string) (conversationID str
 }
 
 // buildAVSContext returns the full context array needed for AVS requests
-func (c *Client) buildAVSContext() []map[string]interface{} {
+func (c *Client) buildAVSContext(conversationID string) []map[string]interface{} {
 	contexts := []map[string]interface{}{
 		{
 			"header": map[string]interface{}{
@@ -1143,7 +1288,7 @@ func (c *Client) buildAVSContext() []map[string]interface{} {
 			},
 			"payload": map[string]interface{}{
 				"dialog": map[string]interface{}{
-					"interface":            "SpeechSynthesizer",
+					"interface":              "SpeechSynthesizer",
 					"idleTimeInMilliseconds": 100000,
 				},
 			},
@@ -1184,13 +1329,13 @@ func (c *Client) buildAVSContext() []map[string]interface{} {
 				"name":      "PlaybackState",
 			},
 			"payload": map[string]interface{}{
-				"state":               "IDLE",
-				"shuffle":             "NOT_SHUFFLED",
-				"repeat":              "NOT_REPEATED",
-				"favorite":            "NOT_RATED",
+				"state":                "IDLE",
+				"shuffle":              "NOT_SHUFFLED",
+				"repeat":               "NOT_REPEATED",
+				"favorite":             "NOT_RATED",
 				"positionMilliseconds": 0,
-				"supportedOperations": []string{"Play", "Pause", "Previous", "Next"},
-				"players":             []interface{}{},
+				"supportedOperations":  []string{"Play", "Pause", "Previous", "Next"},
+				"players":              []interface{}{},
 			},
 		},
 		{
@@ -1276,14 +1421,14 @@ func (c *Client) buildAVSContext() []map[string]interface{} {
 			"height": 932,
 		},
 		"scrollable": map[string]interface{}{
-			"direction":    "vertical",
-			"allowForward": false,
+			"direction":     "vertical",
+			"allowForward":  false,
 			"allowBackward": true,
 		},
 		"elements": []interface{}{},
 	}
-	if c.conversationID != "" {
-		convPayload["conversationId"] = c.conversationID
+	if conversationID != "" {
+		convPayload["conversationId"] = conversationID
 	}
 	contexts = append(contexts, map[string]interface{}{
 		"header": map[string]interface{}{
@@ -1296,39 +1441,38 @@ func (c *Client) buildAVSContext() []map[string]interface{} {
 	return contexts
 }
 
-// GetConversationFragments retrieves the latest conversation fragments
-func (c *Client) GetConversationFragments() (*ConversationResponse, error) {
-	if c.conversationID == "" {
+// GetConversationFragments retrieves the latest fragments of a conversation
+func (c *Client) GetConversationFragments(ctx context.Context, conversationID string) (*ConversationResponse, error) {
+	if conversationID == "" {
 		return nil, fmt.Errorf("no conversation ID set")
 	}
 
-	if err := c.getBearerToken(); err != nil {
+	if err := c.getBearerToken(ctx); err != nil {
 		return nil, fmt.Errorf("failed to get bearer token: %w", err)
 	}
 
 	// Build URL with token if available
 	fragURL := fmt.Sprintf("%s/v1/conversations/%s/fragments/synchronize",
-		c.avsURL(), c.conversationID)
-
-	req, err := http.NewRequest("GET", fragURL, nil)
-	if err != nil {
-		return nil, err
-	}
+		c.avsURL(), url.PathEscape(conversationID))
 
-	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
-	req.Header.Set("Cookie", c.cookies)
-	req.Header.Set("Accept", "application/json")
+	resp, body, err := c.send(ctx, authBearer, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", fragURL, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.Do(req)
+		creds := c.creds()
+		req.Header.Set("Authorization", "Bearer "+creds.bearerToken)
+		req.Header.Set("Cookie", creds.cookies)
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("conversation API error %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("conversation request failed: %w", newAPIError(resp, body))
 	}
 
 	var result ConversationResponse
@@ -1337,7 +1481,7 @@ func (c *Client) GetConversationFragments() (*ConversationResponse, error) {
 	}
 
 	// Debug: show fragment summary
-	if c.verbose {
+	if c.logger.Enabled(ctx, slog.LevelDebug) {
 		agentCount := 0
 		for _, frag := range result.Fragments {
 			if frag.Metadata.Purpose == "AGENT" && frag.Content != nil {
@@ -1347,31 +1491,21 @@ func (c *Client) GetConversationFragments() (*ConversationResponse, error) {
 				}
 			}
 		}
-		c.log("Fragments: %d total, %d AGENT with text", len(result.Fragments), agentCount)
+		c.logger.Debug("fetched conversation fragments", "fragments", len(result.Fragments), "agent_with_text", agentCount)
 	}
 
 	return &result, nil
 }
 
-// InitConversation creates or retrieves a conversation ID
-func (c *Client) InitConversation() error {
-	if c.conversationID != "" {
-		return nil
-	}
-
-	// Generate a new conversation ID in Amazon's format
-	c.conversationID = fmt.Sprintf("amzn1.conversation.%s", generateUUID())
-	return nil
-}
-
-// SetConversationID sets an existing conversation ID
-func (c *Client) SetConversationID(id string) {
-	c.conversationID = id
+// NewConversationID generates a new conversation ID in Amazon's format
+func NewConversationID() string {
+	return fmt.Sprintf("amzn1.conversation.%s", generateUUID())
 }
 
-// SynchronizeState sends a state sync event to AVS (initializes session)
-func (c *Client) SynchronizeState() error {
-	if err := c.getBearerToken(); err != nil {
+// SynchronizeState sends a state sync event to AVS (initializes session).
+// conversationID may be empty.
+func (c *Client) SynchronizeState(ctx context.Context, conversationID string) error {
+	if err := c.getBearerToken(ctx); err != nil {
 		return fmt.Errorf("failed to get bearer token: %w", err)
 	}
 
@@ -1387,11 +1521,15 @@ func (c *Client) SynchronizeState() error {
 			},
 			"payload": map[string]interface{}{},
 		},
-		"context": c.buildAVSContext(),
+		"context": c.buildAVSContext(conversationID),
 	}
 
 	eventJSON, _ := json.Marshal(event)
 
+	if err := c.checkDryRun(PlannedRequest{Method: "POST", URL: c.avsURL() + "/v20160207/events", Body: eventJSON}); err != nil {
+		return err
+	}
+
 	var body bytes.Buffer
 	body.WriteString(fmt.Sprintf("--%s\r\n", boundary))
 	body.WriteString("Content-Disposition: form-data; name=\"metadata\"\r\n")
@@ -1399,34 +1537,30 @@ func (c *Client) SynchronizeState() error {
 	body.Write(eventJSON)
 	body.WriteString(fmt.Sprintf("\r\n--%s--", boundary))
 
-	c.log("SynchronizeState request size: %d bytes", body.Len())
-
-	req, err := http.NewRequest("POST", c.avsURL()+"/v20160207/events", &body)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
-	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("User-Agent", "Alexa/2.2.696573 CFNetwork/3860.200.71 Darwin/25.1.0")
-	req.Header.Set("Priority", "u=3")
-	if c.cookies != "" {
-		req.Header.Set("Cookie", c.cookies)
-	}
+	resp, respBody, err := c.send(ctx, authBearer, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.avsURL()+"/v20160207/events", bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.Do(req)
+		creds := c.creds()
+		req.Header.Set("Authorization", "Bearer "+creds.bearerToken)
+		req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
+		req.Header.Set("Accept", "*/*")
+		req.Header.Set("User-Agent", "Alexa/2.2.696573 CFNetwork/3860.200.71 Darwin/25.1.0")
+		req.Header.Set("Priority", "u=3")
+		if creds.cookies != "" {
+			req.Header.Set("Cookie", creds.cookies)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("SynchronizeState request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	c.log("SynchronizeState response status: %d", resp.StatusCode)
 
 	// 204 No Content is expected for SynchronizeState
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("SynchronizeState error %d: %s", resp.StatusCode, string(respBody))
+		return fmt.Errorf("SynchronizeState failed: %w", newAPIError(resp, respBody))
 	}
 
 	return nil
@@ -1440,32 +1574,31 @@ type Conversation struct {
 }
 
 // GetConversations retrieves all Alexa+ conversations and their associated devices
-func (c *Client) GetConversations() ([]Conversation, error) {
-	if err := c.getBearerToken(); err != nil {
+func (c *Client) GetConversations(ctx context.Context) ([]Conversation, error) {
+	if err := c.getBearerToken(ctx); err != nil {
 		return nil, fmt.Errorf("failed to get bearer token: %w", err)
 	}
 
-	req, err := http.NewRequest("GET", c.avsURL()+"/v1/conversations", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
-	req.Header.Set("Accept", "application/json")
-	if c.cookies != "" {
-		req.Header.Set("Cookie", c.cookies)
-	}
+	resp, body, err := c.send(ctx, authBearer, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.avsURL()+"/v1/conversations", nil)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.Do(req)
+		creds := c.creds()
+		req.Header.Set("Authorization", "Bearer "+creds.bearerToken)
+		req.Header.Set("Accept", "application/json")
+		if creds.cookies != "" {
+			req.Header.Set("Cookie", creds.cookies)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("conversations API error %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("conversations request failed: %w", newAPIError(resp, body))
 	}
 
 	// Response structure: { "conversations": [ { "id": "...", "creation": { "origin": { "name": "..." } } }, ... ] }
@@ -1514,8 +1647,8 @@ func (c *Client) GetConversations() ([]Conversation, error) {
 
 // GetConversationForDevice finds the most recent conversation ID for a device name
 // Returns empty string if no conversation found
-func (c *Client) GetConversationForDevice(deviceName string) (string, error) {
-	conversations, err := c.GetConversations()
+func (c *Client) GetConversationForDevice(ctx context.Context, deviceName string) (string, error) {
+	conversations, err := c.GetConversations(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -1534,43 +1667,40 @@ func (c *Client) GetConversationForDevice(deviceName string) (string, error) {
 	}
 
 	if bestMatch == nil {
-		return "", fmt.Errorf("no Alexa+ conversation found for device %q", deviceName)
+		return "", fmt.Errorf("%w: no Alexa+ conversation found for device %q", ErrDeviceNotFound, deviceName)
 	}
 
 	return bestMatch.ConversationID, nil
 }
 
-// AskPlus sends a question via Alexa+ (LLM) and returns the response
-func (c *Client) AskPlus(question string, timeout time.Duration) (string, error) {
-	c.log("AskPlus: %q (timeout: %v)", question, timeout)
-
+// AskPlus sends a question via Alexa+ (LLM) in a conversation and returns the conversation ID
+// and the response. An empty conversationID starts a new conversation; pass the returned ID
+// to ask a follow-up. Concurrent calls may use different conversations.
+func (c *Client) AskPlus(ctx context.Context, conversationID, question string, timeout time.Duration) (string, string, error) {
 	// First, sync state with AVS
-	c.log("Sending SynchronizeState...")
-	if err := c.SynchronizeState(); err != nil {
-		c.log("SynchronizeState error (continuing anyway): %v", err)
+	if err := c.SynchronizeState(ctx, conversationID); err != nil {
+		c.logger.Debug("SynchronizeState failed, continuing anyway", "error", err)
 	}
 
 	// Send the text message and get conversation ID from response
-	convID, initialText, err := c.SendAVSTextMessageWithResponse(question)
+	convID, initialText, err := c.SendAVSTextMessageWithResponse(ctx, conversationID, question)
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		return conversationID, "", fmt.Errorf("failed to send message: %w", err)
 	}
 
-	// If we got a direct response, return it
-	if initialText != "" {
-		c.log("Got direct response from AVS POST")
-		return initialText, nil
+	// Poll the conversation Alexa answered in, if it told us
+	if convID != "" {
+		conversationID = convID
 	}
 
-	// Set conversation ID for polling
-	if convID != "" {
-		c.conversationID = convID
-		c.log("Set conversation ID for polling: %s", convID)
+	// If we got a direct response, return it
+	if initialText != "" {
+		return conversationID, initialText, nil
 	}
 
 	// If no conversation ID, we can't poll
-	if c.conversationID == "" {
-		return "", fmt.Errorf("no conversation ID received from Alexa")
+	if conversationID == "" {
+		return "", "", fmt.Errorf("no conversation ID received from Alexa")
 	}
 
 	// Poll for new fragments
@@ -1578,36 +1708,30 @@ func (c *Client) AskPlus(question string, timeout time.Duration) (string, error)
 	pollInterval := 500 * time.Millisecond
 	pollCount := 0
 
-	c.log("Starting to poll conversation fragments...")
-
 	for time.Now().Before(endTime) {
-		time.Sleep(pollInterval)
+		if err := SleepContext(ctx, pollInterval); err != nil {
+			return conversationID, "", err
+		}
 		pollCount++
 
-		resp, err := c.GetConversationFragments()
+		resp, err := c.GetConversationFragments(ctx, conversationID)
 		if err != nil {
-			c.log("Poll %d error: %v", pollCount, err)
+			c.logger.Debug("fragment poll failed", "poll", pollCount, "error", err)
 			continue // Keep trying
 		}
 
-		c.log("Poll %d: got %d fragments", pollCount, len(resp.Fragments))
-
 		// Look for AGENT responses with text content
 		for i, frag := range resp.Fragments {
-			hasContent := frag.Content != nil
 			text := ""
-			if hasContent {
+			if frag.Content != nil {
 				text = frag.Content.GetText()
 			}
-			hasText := text != ""
 			if pollCount == 1 && i < 5 {
 				// Dump first 5 fragments on first poll for debugging
-				c.log("Frag[%d]: purpose=%s, uri=%s, hasContent=%v, hasText=%v",
-					i, frag.Metadata.Purpose, frag.FragmentURI, hasContent, hasText)
+				c.logger.Debug("fragment", "index", i, "purpose", frag.Metadata.Purpose,
+					"uri", frag.FragmentURI, "has_text", text != "")
 			}
-			if hasText {
-				c.log("Fragment with text: purpose=%s, uri=%s, text=%q",
-					frag.Metadata.Purpose, frag.FragmentURI, text[:min(50, len(text))])
+			if text != "" {
 
 				if frag.Metadata.Purpose == "AGENT" ||
 					strings.Contains(frag.FragmentURI, "LLM:APE") {
@@ -1628,11 +1752,11 @@ func (c *Client) AskPlus(question string, timeout time.Duration) (string, error)
 							result += "\n" + item.Text
 						}
 					}
-					return result, nil
+					return conversationID, result, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("timeout waiting for Alexa+ response")
+	return conversationID, "", fmt.Errorf("%w (Alexa+)", ErrTimeout)
 }
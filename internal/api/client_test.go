@@ -0,0 +1,163 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/buddyh/alexa-cli/internal/fake"
+)
+
+// newTestClient starts an empty fake backend and returns a client talking to it
+func newTestClient(t *testing.T) (*api.Client, *fake.Server) {
+	t.Helper()
+
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClientWithOptions(context.Background(), "test-refresh-token", "amazon.com", api.Options{
+		Endpoints: srv.Endpoints(),
+		Retry:     &api.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		RateLimit: &api.RateLimit{},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions: %v", err)
+	}
+	return client, srv
+}
+
+func TestGetDevices(t *testing.T) {
+	client, srv := newTestClient(t)
+	kitchen := srv.AddDevice("Kitchen Echo", "ECHO")
+	srv.AddDevice("Office", "ECHO")
+
+	devices, err := client.GetDevices(context.Background())
+	if err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2", len(devices))
+	}
+	if devices[0].AccountName != "Kitchen Echo" || devices[0].SerialNumber != kitchen.SerialNumber {
+		t.Errorf("first device = %s (%s), want Kitchen Echo (%s)", devices[0].AccountName, devices[0].SerialNumber, kitchen.SerialNumber)
+	}
+
+	dev, err := client.FindDevice(context.Background(), "office")
+	if err != nil {
+		t.Fatalf("FindDevice: %v", err)
+	}
+	if dev.AccountName != "Office" {
+		t.Errorf("FindDevice(office) = %s, want Office", dev.AccountName)
+	}
+
+	if _, err := client.FindDevice(context.Background(), "Garage"); !errors.Is(err, api.ErrDeviceNotFound) {
+		t.Errorf("FindDevice(Garage) error = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestSpeak(t *testing.T) {
+	client, srv := newTestClient(t)
+	kitchen := srv.AddDevice("Kitchen Echo", "ECHO")
+
+	seq := api.NewSequence(client.SpeakOperation(&kitchen, "Dinner is ready"))
+	if err := client.RunSequence(context.Background(), seq); err != nil {
+		t.Fatalf("RunSequence: %v", err)
+	}
+
+	sequences := srv.Sequences()
+	if len(sequences) != 1 || len(sequences[0].Operations) != 1 {
+		t.Fatalf("got %d sequences, want one with a single operation", len(sequences))
+	}
+	op := sequences[0].Operations[0]
+	if op.Type != "Alexa.Speak" {
+		t.Errorf("operation type = %s, want Alexa.Speak", op.Type)
+	}
+	if got := op.Payload["textToSpeak"]; got != "Dinner is ready" {
+		t.Errorf("textToSpeak = %v, want Dinner is ready", got)
+	}
+	if got := op.Payload["deviceSerialNumber"]; got != kitchen.SerialNumber {
+		t.Errorf("deviceSerialNumber = %v, want %s", got, kitchen.SerialNumber)
+	}
+	if got := op.Payload["customerId"]; got != fake.CustomerID {
+		t.Errorf("customerId = %v, want %s", got, fake.CustomerID)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	client, srv := newTestClient(t)
+	kitchen := srv.AddDevice("Kitchen Echo", "ECHO")
+	srv.Answer("what time is it", "It's noon.")
+
+	answer, err := client.Ask(context.Background(), &kitchen, "what time is it", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if answer != "It's noon." {
+		t.Errorf("Ask = %q, want %q", answer, "It's noon.")
+	}
+}
+
+func TestAskPlus(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddDevice("Kitchen Echo", "ECHO")
+	convID := srv.AddConversation("Kitchen Echo")
+	srv.SetResponder(func(text string) string { return "Echo: " + text })
+
+	gotID, answer, err := client.AskPlus(context.Background(), convID, "tell me a story", 5*time.Second)
+	if err != nil {
+		t.Fatalf("AskPlus: %v", err)
+	}
+	if gotID != convID {
+		t.Errorf("conversation ID = %s, want %s", gotID, convID)
+	}
+	if answer != "Echo: tell me a story" {
+		t.Errorf("AskPlus = %q, want %q", answer, "Echo: tell me a story")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddDevice("Kitchen Echo", "ECHO")
+
+	if _, err := client.GetDevices(context.Background()); err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	srv.ExpireSession()
+	if _, err := client.GetDevices(context.Background()); err != nil {
+		t.Fatalf("GetDevices after the session expired: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	client, srv := newTestClient(t)
+	kitchen := srv.AddDevice("Kitchen Echo", "ECHO")
+
+	// Reads are retried through server errors
+	srv.FailNext("/api/devices-v2/device", http.StatusServiceUnavailable, http.StatusBadGateway)
+	if _, err := client.GetDevices(context.Background()); err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+
+	// A sequence that may already have run is not sent again
+	srv.FailNext("/api/behaviors/preview", http.StatusInternalServerError)
+	seq := api.NewSequence(client.SpeakOperation(&kitchen, "Hello"))
+	var apiErr *api.APIError
+	if err := client.RunSequence(context.Background(), seq); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("RunSequence error = %v, want a 500 APIError", err)
+	}
+	if n := len(srv.Sequences()); n != 0 {
+		t.Errorf("fake received %d sequences after a 500, want 0", n)
+	}
+
+	// Throttled requests were never acted on, so they are retried
+	srv.FailNext("/api/behaviors/preview", http.StatusTooManyRequests)
+	if err := client.RunSequence(context.Background(), seq); err != nil {
+		t.Fatalf("RunSequence after 429: %v", err)
+	}
+	if n := len(srv.Sequences()); n != 1 {
+		t.Errorf("fake received %d sequences, want 1", n)
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import "encoding/json"
+
+// PlannedRequest is a state-changing request that a dry run did not send
+type PlannedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body"` // JSON body, or the event metadata of an AVS request
+
+	// Sequence is the sequenceJson of a behaviors/preview request, decoded so
+	// it can be read without unescaping the string in Body
+	Sequence json.RawMessage `json:"sequence,omitempty"`
+}
+
+// newPlannedRequest describes a request with a JSON body
+func newPlannedRequest(method, url string, body interface{}) PlannedRequest {
+	data, err := json.Marshal(body)
+	if err != nil {
+		// The real request would fail to encode too; show what we can
+		data, _ = json.Marshal(err.Error())
+	}
+	return PlannedRequest{Method: method, URL: url, Body: data}
+}
+
+// checkDryRun hands req to Options.DryRun and returns ErrDryRun when a dry
+// run is in progress, so the caller returns instead of sending it
+func (c *Client) checkDryRun(req PlannedRequest) error {
+	if c.dryRun == nil {
+		return nil
+	}
+	c.dryRun(req)
+	return ErrDryRun
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Endpoints holds the base URLs (scheme and host, no trailing slash) the client talks to.
+// Empty fields are filled in from DefaultEndpoints for the account's Amazon domain.
+type Endpoints struct {
+	API      string `json:"api,omitempty"`      // pitangui/layla: devices, behaviors, phoenix
+	Alexa    string `json:"alexa,omitempty"`    // alexa.<domain>: CSRF, routines
+	Activity string `json:"activity,omitempty"` // www.<domain>: voice history
+	AVS      string `json:"avs,omitempty"`      // AVS events and Alexa+ conversations
+	Auth     string `json:"auth,omitempty"`     // api.amazon.com: token exchange
+}
+
+// DefaultEndpoints returns Amazon's endpoints for an Amazon domain such as "amazon.de"
+func DefaultEndpoints(amazonDomain string) Endpoints {
+	return Endpoints{
+		API:      defaultAPIURL(amazonDomain),
+		Alexa:    fmt.Sprintf("https://alexa.%s", amazonDomain),
+		Activity: fmt.Sprintf("https://www.%s", amazonDomain),
+		AVS:      defaultAVSURL(amazonDomain),
+		Auth:     "https://api.amazon.com",
+	}
+}
+
+// withDefaults fills empty fields from the defaults for amazonDomain
+func (e Endpoints) withDefaults(amazonDomain string) Endpoints {
+	def := DefaultEndpoints(amazonDomain)
+	fill := func(v, fallback string) string {
+		if v == "" {
+			return fallback
+		}
+		return strings.TrimRight(v, "/")
+	}
+
+	return Endpoints{
+		API:      fill(e.API, def.API),
+		Alexa:    fill(e.Alexa, def.Alexa),
+		Activity: fill(e.Activity, def.Activity),
+		AVS:      fill(e.AVS, def.AVS),
+		Auth:     fill(e.Auth, def.Auth),
+	}
+}
+
+// defaultAPIURL returns the Alexa API base URL for a domain
+func defaultAPIURL(amazonDomain string) string {
+	// pitangui for US, layla for EU/UK
+	// The subdomain changes based on region, but the TLD matches the user's amazon domain
+	switch amazonDomain {
+	case "amazon.com":
+		return "https://pitangui.amazon.com"
+	case "amazon.co.uk":
+		return "https://layla.amazon.co.uk"
+	case "amazon.de":
+		return "https://layla.amazon.de"
+	case "amazon.fr":
+		return "https://layla.amazon.fr"
+	case "amazon.it":
+		return "https://layla.amazon.it"
+	case "amazon.es":
+		return "https://layla.amazon.es"
+	case "amazon.co.jp":
+		return "https://layla.amazon.co.jp"
+	case "amazon.com.au":
+		return "https://alexa.amazon.com.au"
+	case "amazon.ca":
+		return "https://pitangui.amazon.ca"
+	case "amazon.com.br":
+		return "https://pitangui.amazon.com.br"
+	case "amazon.in":
+		return "https://pitangui.amazon.in"
+	default:
+		// Fall back to layla with the user's domain
+		return fmt.Sprintf("https://layla.%s", amazonDomain)
+	}
+}
+
+// defaultAVSURL returns the AVS API base URL for a domain
+func defaultAVSURL(amazonDomain string) string {
+	// AVS has regional endpoints
+	switch amazonDomain {
+	case "amazon.com", "amazon.ca", "amazon.com.br":
+		return "https://avs-alexa-12-na.amazon.com"
+	case "amazon.co.uk", "amazon.de", "amazon.fr", "amazon.it", "amazon.es", "amazon.in":
+		return "https://avs-alexa-eu.amazon.com"
+	case "amazon.co.jp":
+		return "https://avs-alexa-fe.amazon.com"
+	default:
+		// Default to EU for unknown domains (safer for most non-US users)
+		return "https://avs-alexa-eu.amazon.com"
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors returned (possibly wrapped) by Client methods. Use errors.Is to test for them.
+var (
+	// ErrAuthExpired means Amazon rejected our credentials and logging in again did not help.
+	// Usually the refresh token has expired and 'alexacli auth' needs to be re-run.
+	ErrAuthExpired = errors.New("authentication expired")
+
+	// ErrDeviceNotFound means no device matched the given name or serial
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrDeviceOffline means the targeted device is not connected to Amazon
+	ErrDeviceOffline = errors.New("device is offline")
+
+	// ErrAmbiguousMatch means a name matched several devices and none exactly
+	ErrAmbiguousMatch = errors.New("ambiguous match")
+
+	// ErrTimeout means Alexa did not answer within the allowed time
+	ErrTimeout = errors.New("timeout waiting for Alexa response")
+
+	// ErrRateLimited means Amazon is throttling requests
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrDryRun means a state-changing request was handed to Options.DryRun instead of being sent
+	ErrDryRun = errors.New("dry run: request not sent")
+)
+
+// APIError is returned when an Alexa endpoint responds with an error status
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Endpoint   string // host and path of the failing request
+	Body       string // raw response body
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d from %s: %s", e.StatusCode, e.Endpoint, e.Body)
+}
+
+// Is lets errors.Is match an APIError against the sentinel for its status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAuthExpired:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrDeviceOffline:
+		return isOfflineMessage(e.Body)
+	}
+	return false
+}
+
+// isOfflineMessage reports whether an error body is Amazon saying the device
+// is unreachable, e.g. {"message":"Device is offline"} or a DEVICE_OFFLINE code
+func isOfflineMessage(body string) bool {
+	body = strings.ToLower(body)
+	return strings.Contains(body, "offline") || strings.Contains(body, "not online")
+}
+
+// newAPIError builds an APIError from a failed response
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	endpoint := ""
+	if resp.Request != nil {
+		endpoint = resp.Request.URL.Host + resp.Request.URL.Path
+	}
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Endpoint:   endpoint,
+		Body:       string(body),
+	}
+}
+
+// newAuthError builds the error for a failed token exchange. A 4xx from the
+// auth endpoints means the refresh token itself is no longer accepted.
+func newAuthError(resp *http.Response, body []byte) error {
+	apiErr := newAPIError(resp, body)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", ErrAuthExpired, apiErr)
+	}
+	return apiErr
+}
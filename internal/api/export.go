@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RoutineExport is a routine as written by routine export: the automation
+// exactly as Amazon stores it, plus the devices it refers to so that it can be
+// restored to another account or after hardware has been replaced
+type RoutineExport struct {
+	Name         string           `json:"name"`
+	AutomationID string           `json:"automationId"`
+	Automation   json.RawMessage  `json:"automation"`
+	Devices      []ExportedDevice `json:"devices"`
+}
+
+// ExportedDevice is a device a routine referred to when it was exported
+type ExportedDevice struct {
+	SerialNumber string `json:"serialNumber"`
+	DeviceType   string `json:"deviceType"`
+	Name         string `json:"name"` // empty if the device wasn't on the account
+}
+
+// ExportRoutine packages a routine with the devices it refers to, named from devices
+func ExportRoutine(r Routine, devices []Device) (*RoutineExport, error) {
+	automation := r.Raw
+	if len(automation) == 0 {
+		// Not read from Amazon, so there is nothing beyond what Routine holds
+		var err error
+		if automation, err = json.Marshal(routineBodyFor(&r)); err != nil {
+			return nil, err
+		}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(automation, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse routine '%s': %w", r.Name, err)
+	}
+
+	serials := make(map[string]string) // serial -> device type seen in the routine
+	walkDeviceRefs(v, func(ref map[string]interface{}, serial string) {
+		if serials[serial] == "" {
+			serials[serial] = deviceTypeOf(ref)
+		}
+	})
+
+	exp := &RoutineExport{
+		Name:         r.Name,
+		AutomationID: r.AutomationID,
+		Automation:   automation,
+		Devices:      []ExportedDevice{},
+	}
+	for serial, deviceType := range serials {
+		d := ExportedDevice{SerialNumber: serial, DeviceType: deviceType}
+		for _, dev := range devices {
+			if dev.SerialNumber == serial {
+				d.DeviceType = dev.DeviceType
+				d.Name = dev.AccountName
+				break
+			}
+		}
+		exp.Devices = append(exp.Devices, d)
+	}
+	sort.Slice(exp.Devices, func(i, j int) bool { return exp.Devices[i].SerialNumber < exp.Devices[j].SerialNumber })
+
+	return exp, nil
+}
+
+// RemapRoutine turns an exported routine back into a new routine for this
+// account. Devices are matched to devices by name, ignoring case, or failing
+// that by serial number, and the customer ID is replaced with this account's.
+// It returns a description of each device that couldn't be matched; references
+// to those are left as they were.
+func (c *Client) RemapRoutine(exp *RoutineExport, devices []Device) (*Routine, []string, error) {
+	if len(devices) == 0 {
+		return nil, nil, fmt.Errorf("%w: no devices on this account", ErrDeviceNotFound)
+	}
+	customerID := c.customerIDFor(&devices[0])
+
+	mapping := make(map[string]*Device)
+	var unmapped []string
+	for _, old := range exp.Devices {
+		var match *Device
+		for i := range devices {
+			if old.Name != "" && strings.EqualFold(devices[i].AccountName, old.Name) {
+				match = &devices[i]
+				break
+			}
+		}
+		// Restoring to the same account, the device may just have been renamed,
+		// or have had no name when it was exported
+		if match == nil {
+			for i := range devices {
+				if devices[i].SerialNumber == old.SerialNumber {
+					match = &devices[i]
+					break
+				}
+			}
+		}
+		if match == nil {
+			if old.Name == "" {
+				unmapped = append(unmapped, fmt.Sprintf("unnamed device %s", old.SerialNumber))
+			} else {
+				unmapped = append(unmapped, fmt.Sprintf("'%s' (%s)", old.Name, old.SerialNumber))
+			}
+			continue
+		}
+		mapping[old.SerialNumber] = match
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(exp.Automation, &v); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse routine '%s': %w", exp.Name, err)
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, nil, fmt.Errorf("routine '%s' has no automation", exp.Name)
+	}
+
+	walkDeviceRefs(v, func(ref map[string]interface{}, serial string) {
+		dev, ok := mapping[serial]
+		if !ok {
+			return
+		}
+		ref["deviceSerialNumber"] = dev.SerialNumber
+		for _, key := range []string{"deviceType", "deviceTypeId"} {
+			if _, ok := ref[key]; ok {
+				ref[key] = dev.DeviceType
+			}
+		}
+	})
+	replaceCustomerID(v, customerID)
+
+	remapped, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := decodeAutomation(remapped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse routine '%s': %w", exp.Name, err)
+	}
+	// The ID belongs to the exported routine; a new one is issued when this is created
+	r.AutomationID = ""
+
+	return r, unmapped, nil
+}
+
+// walkDeviceRefs calls fn for every object under v that names a device by serial
+// number, other than Amazon's all-devices placeholder
+func walkDeviceRefs(v interface{}, fn func(ref map[string]interface{}, serial string)) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if serial, ok := node["deviceSerialNumber"].(string); ok && serial != "" && serial != "ALEXA_ALL_DSN" {
+			fn(node, serial)
+		}
+		for _, child := range node {
+			walkDeviceRefs(child, fn)
+		}
+	case []interface{}:
+		for _, child := range node {
+			walkDeviceRefs(child, fn)
+		}
+	}
+}
+
+// replaceCustomerID sets every customerId under v to id
+func replaceCustomerID(v interface{}, id string) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if _, isString := child.(string); key == "customerId" && isString {
+				node[key] = id
+				continue
+			}
+			replaceCustomerID(child, id)
+		}
+	case []interface{}:
+		for _, child := range node {
+			replaceCustomerID(child, id)
+		}
+	}
+}
+
+func deviceTypeOf(ref map[string]interface{}) string {
+	for _, key := range []string{"deviceType", "deviceTypeId"} {
+		if t, ok := ref[key].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
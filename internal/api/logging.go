@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Attribute keys whose values are always credentials
+var secretLogKeys = map[string]bool{
+	"cookie":        true,
+	"cookies":       true,
+	"csrf":          true,
+	"token":         true,
+	"bearer":        true,
+	"authorization": true,
+	"refresh_token": true,
+	"access_token":  true,
+}
+
+// secretSet collects the credentials a client holds so they can be scrubbed from logs.
+// It has its own lock so logging never contends with the client's state.
+type secretSet struct {
+	mu     sync.Mutex
+	values map[string]bool
+	scrub  *strings.Replacer // rebuilt when values change
+}
+
+// add remembers credential values. Very short values are skipped since
+// replacing them would mangle unrelated text.
+func (s *secretSet) add(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range values {
+		if len(v) < 8 || s.values[v] {
+			continue
+		}
+		if s.values == nil {
+			s.values = make(map[string]bool)
+		}
+		s.values[v] = true
+		s.scrub = nil
+	}
+}
+
+// addCookies remembers every value in a "name=value; name=value" cookie string
+func (s *secretSet) addCookies(cookies string) {
+	for _, part := range strings.Split(cookies, ";") {
+		if _, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			s.add(value)
+		}
+	}
+}
+
+// replace returns text with every known credential replaced by REDACTED
+func (s *secretSet) replace(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.values) == 0 {
+		return text
+	}
+	if s.scrub == nil {
+		pairs := make([]string, 0, 2*len(s.values))
+		for v := range s.values {
+			pairs = append(pairs, v, redacted)
+		}
+		s.scrub = strings.NewReplacer(pairs...)
+	}
+	return s.scrub.Replace(text)
+}
+
+// redactHandler wraps a slog.Handler, blanking credential attributes and
+// scrubbing known credential values from messages and string attributes
+type redactHandler struct {
+	next    slog.Handler
+	secrets *secretSet
+}
+
+// newLogger returns a logger writing to base's handler through a redactHandler.
+// A nil base discards everything.
+func newLogger(base *slog.Logger, secrets *secretSet) *slog.Logger {
+	if base == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return slog.New(&redactHandler{next: base.Handler(), secrets: secrets})
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.secrets.replace(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = h.redact(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redactedAttrs), secrets: h.secrets}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), secrets: h.secrets}
+}
+
+// redact returns a with any credential removed
+func (h *redactHandler) redact(a slog.Attr) slog.Attr {
+	if secretLogKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = h.redact(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	case slog.KindString:
+		return slog.String(a.Key, h.secrets.replace(v.String()))
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.secrets.replace(err.Error()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// NowPlaying is the media state of a device, from the player endpoint
+type NowPlaying struct {
+	State    string        `json:"state"` // PLAYING, PAUSED or IDLE
+	Title    string        `json:"title,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	Album    string        `json:"album,omitempty"`
+	Provider string        `json:"provider,omitempty"`
+	Position time.Duration `json:"-"`
+	Length   time.Duration `json:"-"`
+	Volume   int           `json:"volume"`
+	Muted    bool          `json:"muted"`
+	Shuffle  bool          `json:"shuffle"`
+	Repeat   bool          `json:"repeat"`
+}
+
+// playerResponse is the raw /api/np/player response
+type playerResponse struct {
+	PlayerInfo *struct {
+		State    string `json:"state"`
+		InfoText *struct {
+			Title    string `json:"title"`
+			SubText1 string `json:"subText1"` // usually the artist
+			SubText2 string `json:"subText2"` // usually the album
+		} `json:"infoText"`
+		Provider *struct {
+			ProviderName string `json:"providerName"`
+		} `json:"provider"`
+		Progress *struct {
+			MediaLength   int64 `json:"mediaLength"`   // seconds
+			MediaProgress int64 `json:"mediaProgress"` // seconds
+		} `json:"progress"`
+		Volume *struct {
+			Volume int  `json:"volume"`
+			Muted  bool `json:"muted"`
+		} `json:"volume"`
+		Transport *struct {
+			Shuffle string `json:"shuffle"` // SELECTED when on
+			Repeat  string `json:"repeat"`
+		} `json:"transport"`
+	} `json:"playerInfo"`
+}
+
+// playerQuery returns the query string identifying device to the player endpoints
+func playerQuery(device *Device) string {
+	return url.Values{
+		"deviceSerialNumber": {device.SerialNumber},
+		"deviceType":         {device.DeviceType},
+	}.Encode()
+}
+
+// GetNowPlaying returns what a device is playing
+func (c *Client) GetNowPlaying(ctx context.Context, device *Device) (*NowPlaying, error) {
+	data, err := c.request(ctx, "GET", "/api/np/player?"+playerQuery(device)+"&screenWidth=1440", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result playerResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse player state: %w", err)
+	}
+
+	np := &NowPlaying{State: "IDLE"}
+	info := result.PlayerInfo
+	if info == nil {
+		return np, nil
+	}
+	if info.State != "" {
+		np.State = info.State
+	}
+	if info.InfoText != nil {
+		np.Title = info.InfoText.Title
+		np.Artist = info.InfoText.SubText1
+		np.Album = info.InfoText.SubText2
+	}
+	if info.Provider != nil {
+		np.Provider = info.Provider.ProviderName
+	}
+	if info.Progress != nil {
+		np.Position = time.Duration(info.Progress.MediaProgress) * time.Second
+		np.Length = time.Duration(info.Progress.MediaLength) * time.Second
+	}
+	if info.Volume != nil {
+		np.Volume = info.Volume.Volume
+		np.Muted = info.Volume.Muted
+	}
+	if info.Transport != nil {
+		np.Shuffle = info.Transport.Shuffle == "SELECTED"
+		np.Repeat = info.Transport.Repeat == "SELECTED"
+	}
+
+	return np, nil
+}
+
+// MediaCommand is a transport control accepted by SendMediaCommand
+type MediaCommand string
+
+const (
+	MediaPlay     MediaCommand = "PlayCommand"
+	MediaPause    MediaCommand = "PauseCommand"
+	MediaNext     MediaCommand = "NextCommand"
+	MediaPrevious MediaCommand = "PreviousCommand"
+)
+
+// SendMediaCommand sends a transport control to the player on device
+func (c *Client) SendMediaCommand(ctx context.Context, device *Device, command MediaCommand) error {
+	return c.playerCommand(ctx, device, map[string]interface{}{"type": string(command)})
+}
+
+// SeekMedia moves playback on device to position
+func (c *Client) SeekMedia(ctx context.Context, device *Device, position time.Duration) error {
+	return c.playerCommand(ctx, device, map[string]interface{}{
+		"type":          "SeekCommand",
+		"mediaPosition": position.Milliseconds(),
+	})
+}
+
+// SetShuffle turns shuffle on or off on device
+func (c *Client) SetShuffle(ctx context.Context, device *Device, on bool) error {
+	return c.playerCommand(ctx, device, map[string]interface{}{"type": "ShuffleCommand", "shuffle": on})
+}
+
+// SetRepeat turns repeat on or off on device
+func (c *Client) SetRepeat(ctx context.Context, device *Device, on bool) error {
+	return c.playerCommand(ctx, device, map[string]interface{}{"type": "RepeatCommand", "repeat": on})
+}
+
+// playerCommand posts a command to the now-playing command endpoint
+func (c *Client) playerCommand(ctx context.Context, device *Device, command map[string]interface{}) error {
+	endpoint := "/api/np/command?" + playerQuery(device)
+	if err := c.checkDryRun(newPlannedRequest("POST", c.baseURL()+endpoint, command)); err != nil {
+		return err
+	}
+
+	_, err := c.request(ctx, "POST", endpoint, command)
+	return err
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Host selects which Amazon host a raw request goes to, and so which credentials it carries
+type Host string
+
+const (
+	HostAPI   Host = "layla" // pitangui/layla: cookies and CSRF
+	HostAlexa Host = "alexa" // alexa.<domain>: cookies and CSRF
+	HostAVS   Host = "avs"   // AVS: bearer token
+	HostWWW   Host = "www"   // www.<domain>: cookies and the activity CSRF token
+)
+
+// ParseHost returns the Host for a name as accepted by the api command
+func ParseHost(name string) (Host, error) {
+	switch h := Host(strings.ToLower(name)); h {
+	case HostAPI, HostAlexa, HostAVS, HostWWW:
+		return h, nil
+	case "pitangui":
+		return HostAPI, nil
+	default:
+		return "", fmt.Errorf("unknown host %q (use layla, alexa, avs or www)", name)
+	}
+}
+
+// Raw sends an authenticated request for path (which may include a query) to host and
+// returns the response body. body, if not nil, is sent as JSON. Error statuses are
+// returned as an *APIError holding the body. In a dry run, anything but GET and HEAD
+// is handed to Options.DryRun instead of being sent.
+func (c *Client) Raw(ctx context.Context, host Host, method, path string, body []byte) ([]byte, error) {
+	method = strings.ToUpper(method)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var baseURL string
+	kind := authCookies
+	switch host {
+	case HostAPI:
+		baseURL = c.baseURL()
+	case HostAlexa:
+		baseURL = c.alexaURL()
+	case HostAVS:
+		baseURL = c.avsURL()
+		kind = authBearer
+		if err := c.getBearerToken(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get bearer token: %w", err)
+		}
+	case HostWWW:
+		baseURL = c.endpoints.Activity
+		kind = authActivity
+		if c.creds().activityCSRF == "" {
+			if err := c.fetchActivityCSRF(ctx); err != nil {
+				return nil, fmt.Errorf("failed to get activity CSRF: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown host %q", host)
+	}
+	fullURL := baseURL + path
+
+	if method != "GET" && method != "HEAD" {
+		planned := PlannedRequest{Method: method, URL: fullURL, Body: body}
+		if body != nil && !json.Valid(body) {
+			planned.Body, _ = json.Marshal(string(body))
+		}
+		if err := c.checkDryRun(planned); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, respBody, err := c.send(ctx, kind, func() (*http.Request, error) {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+		if err != nil {
+			return nil, err
+		}
+
+		creds := c.creds()
+		switch kind {
+		case authBearer:
+			req.Header.Set("Authorization", "Bearer "+creds.bearerToken)
+		case authActivity:
+			req.Header.Set("anti-csrftoken-a2z", creds.activityCSRF)
+			req.Header.Set("Origin", baseURL)
+			fallthrough
+		default:
+			req.Header.Set("csrf", creds.csrf)
+		}
+		if creds.cookies != "" {
+			req.Header.Set("Cookie", creds.cookies)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json, */*")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newAPIError(resp, respBody)
+	}
+
+	return respBody, nil
+}
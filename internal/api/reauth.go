@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// authKind identifies which credentials a request depends on, and so what
+// has to be refreshed when Amazon rejects them
+type authKind int
+
+const (
+	authCookies  authKind = iota // session cookies + csrf (pitangui/layla, alexa.*)
+	authActivity                 // cookies + activity page anti-csrf token (www.*)
+	authBearer                   // Atna| bearer token (AVS)
+)
+
+// credentials is a consistent snapshot of the client's credentials
+type credentials struct {
+	cookies      string
+	csrf         string
+	activityCSRF string
+	bearerToken  string
+}
+
+// creds returns the current credentials. Request builders take one snapshot
+// so that a concurrent re-login can't leave them with a mix of old and new values.
+func (c *Client) creds() credentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return credentials{
+		cookies:      c.cookies,
+		csrf:         c.csrf,
+		activityCSRF: c.activityCSRF,
+		bearerToken:  c.bearerToken,
+	}
+}
+
+// generation returns a counter that changes whenever the credentials for kind are replaced
+func (c *Client) generation(kind authKind) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if kind == authBearer {
+		return c.bearerGen
+	}
+	return c.cookieGen
+}
+
+// send performs the request built by newReq and returns the response with its body fully read.
+// If the response shows the session has expired, the credentials for kind are refreshed
+// and the request is rebuilt and replayed once. newReq is called again for the replay so
+// it must read credentials from the client rather than capturing them.
+func (c *Client) send(ctx context.Context, kind authKind, newReq func() (*http.Request, error)) (*http.Response, []byte, error) {
+	gen := c.generation(kind)
+	resp, body, err := c.sendOnce(newReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !isAuthFailure(resp, body) {
+		return resp, body, nil
+	}
+
+	c.logger.Debug("auth failure, re-authenticating and retrying once",
+		"method", resp.Request.Method, "host", resp.Request.URL.Host,
+		"endpoint", resp.Request.URL.Path, "status", resp.StatusCode)
+
+	if err := c.reauthenticate(ctx, kind, gen); err != nil {
+		return nil, nil, fmt.Errorf("session expired and re-authentication failed: %w", err)
+	}
+
+	return c.sendOnce(newReq)
+}
+
+// sendOnce builds and performs a single request
+func (c *Client) sendOnce(newReq func() (*http.Request, error)) (*http.Response, []byte, error) {
+	req, err := newReq()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// isAuthFailure reports whether a response means our cookies or tokens are no longer accepted
+func isAuthFailure(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return true
+	}
+
+	// Expired cookies usually get redirected to the sign-in page rather than a 401
+	if resp.Request != nil && strings.Contains(resp.Request.URL.Path, "/ap/signin") {
+		return true
+	}
+
+	// A JSON endpoint answering with an HTML login form means the same thing.
+	// Pages we fetch as HTML are skipped since they can link to sign-in legitimately.
+	if resp.Request != nil && !strings.Contains(resp.Request.Header.Get("Accept"), "text/html") &&
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") &&
+		strings.Contains(string(body), "/ap/signin") {
+		return true
+	}
+
+	return false
+}
+
+// reauthenticate refreshes the credentials a request of the given kind depends on.
+// gen is the generation the failed request was sent with; if another request has
+// refreshed the credentials since, they are reused rather than logging in again.
+func (c *Client) reauthenticate(ctx context.Context, kind authKind, gen int) error {
+	c.authMu.Lock()
+	if c.generation(kind) != gen {
+		c.logger.Debug("credentials already refreshed by another request")
+	} else {
+		var err error
+		if kind == authBearer {
+			err = c.fetchBearerToken(ctx)
+		} else {
+			err = c.authenticate(ctx, c.refreshToken)
+		}
+		if err != nil {
+			c.authMu.Unlock()
+			return err
+		}
+	}
+	c.authMu.Unlock()
+
+	// Fetched outside authMu since the page request can itself need a re-login
+	if kind == authActivity {
+		if err := c.fetchActivityCSRF(ctx); err != nil {
+			return fmt.Errorf("failed to get activity CSRF: %w", err)
+		}
+	}
+
+	c.saveSession(ctx)
+	c.logger.Debug("re-authentication succeeded")
+	return nil
+}
@@ -0,0 +1,493 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// redacted replaces every secret in a recording
+const redacted = "REDACTED"
+
+// Headers whose whole value is a credential
+var secretHeaders = map[string]bool{
+	"authorization":      true,
+	"csrf":               true,
+	"anti-csrftoken-a2z": true,
+	"x-amz-access-token": true,
+}
+
+// JSON keys and form fields whose value is a credential
+var secretFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"source_token":  true,
+	"csrfToken":     true,
+	"Value":         true, // cookie values in the token exchange response
+}
+
+// har is the subset of the HTTP Archive 1.2 format used for recordings
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	PostData    *harPost    `json:"postData,omitempty"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPost struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary bodies
+	Comment  string `json:"comment,omitempty"`
+}
+
+// recordingTransport passes requests through to the network and writes every
+// exchange, with credentials redacted, to a HAR file
+type recordingTransport struct {
+	base http.RoundTripper
+	path string
+	log  *slog.Logger
+
+	mu      sync.Mutex
+	entries []harEntry
+	secrets map[string]bool // credential values seen so far, scrubbed from every entry
+}
+
+// newRecordingTransport creates the recording file and returns a transport writing to it.
+// secrets are values known up front, such as the refresh token.
+func newRecordingTransport(base http.RoundTripper, path string, log *slog.Logger, secrets ...string) (*recordingTransport, error) {
+	t := &recordingTransport{
+		base:    base,
+		path:    path,
+		log:     log,
+		secrets: make(map[string]bool),
+	}
+	for _, s := range secrets {
+		t.addSecret(s)
+	}
+
+	if err := t.write(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	// Compressed bodies are kept decompressed so credentials in them can be scrubbed.
+	// A body that can't be decompressed can't be scrubbed either, so it is left out.
+	stored, respHeader, comment := respBody, resp.Header, ""
+	if ce := resp.Header.Get("Content-Encoding"); ce != "" && ce != "identity" {
+		respHeader = resp.Header.Clone()
+		respHeader.Del("Content-Encoding")
+		respHeader.Del("Content-Length")
+		if stored, err = decompress(ce, respBody); err != nil {
+			stored = nil
+			comment = fmt.Sprintf("%s body not recorded: it could not be decoded to remove credentials", ce)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := harEntry{
+		StartedDateTime: start,
+		Time:            float64(time.Since(start).Microseconds()) / 1000,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     t.redactHeaders(req.Header),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     t.redactHeaders(respHeader),
+			Content: harContent{
+				Size:     len(stored),
+				MimeType: resp.Header.Get("Content-Type"),
+				Comment:  comment,
+			},
+		},
+	}
+	if utf8.Valid(stored) {
+		entry.Response.Content.Text = t.redactBody(resp.Header.Get("Content-Type"), stored)
+	} else {
+		// Binary, e.g. audio in an AVS multipart response. Secrets are scrubbed
+		// from the raw bytes when the file is written.
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(stored)
+		entry.Response.Content.Encoding = "base64"
+	}
+	if reqBody != nil {
+		entry.Request.PostData = &harPost{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     t.redactBody(req.Header.Get("Content-Type"), reqBody),
+		}
+	}
+	t.entries = append(t.entries, entry)
+
+	// Rewrite the whole file each time so a crash or Ctrl-C still leaves a usable recording
+	if err := t.write(); err != nil {
+		t.log.Warn("failed to write recording", "path", t.path, "error", err)
+	}
+
+	return resp, nil
+}
+
+// addSecret remembers a credential so it is scrubbed wherever it appears.
+// Very short values are skipped since replacing them would mangle unrelated text.
+// The caller must hold t.mu.
+func (t *recordingTransport) addSecret(s string) {
+	if len(s) >= 8 && s != redacted {
+		t.secrets[s] = true
+	}
+}
+
+// redactHeaders converts headers to HAR form, blanking credentials but keeping
+// cookie names so a replayed session still parses. The caller must hold t.mu.
+func (t *recordingTransport) redactHeaders(h http.Header) []harHeader {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []harHeader
+	for _, name := range names {
+		for _, value := range h[name] {
+			switch lower := strings.ToLower(name); {
+			case secretHeaders[lower]:
+				t.addSecret(strings.TrimPrefix(value, "Bearer "))
+				value = redacted
+			case lower == "cookie":
+				value = t.redactCookies(value)
+			case lower == "set-cookie":
+				// Only the first pair is the cookie; the rest are attributes
+				pair, attrs, _ := strings.Cut(value, ";")
+				value = t.redactCookies(pair)
+				if attrs != "" {
+					value += ";" + attrs
+				}
+			}
+			out = append(out, harHeader{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+// redactCookies blanks the values in a "name=value; name=value" list. The caller must hold t.mu.
+func (t *recordingTransport) redactCookies(list string) string {
+	parts := strings.Split(list, ";")
+	for i, part := range parts {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		t.addSecret(value)
+		parts[i] = name + "=" + redacted
+	}
+	return strings.Join(parts, "; ")
+}
+
+// redactBody blanks credential fields in JSON and form bodies. Other bodies are
+// only scrubbed of known secrets when the file is written. The caller must hold t.mu.
+func (t *recordingTransport) redactBody(contentType string, body []byte) string {
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			break
+		}
+		for key, values := range form {
+			if secretFields[key] {
+				for _, v := range values {
+					t.addSecret(v)
+				}
+				form[key] = []string{redacted}
+			}
+		}
+		return form.Encode()
+
+	case strings.HasPrefix(contentType, "application/json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			break
+		}
+		v = t.redactJSON(v)
+		out, err := json.Marshal(v)
+		if err != nil {
+			break
+		}
+		return string(out)
+	}
+
+	return string(body)
+}
+
+// redactJSON blanks credential fields anywhere in a decoded JSON value. The caller must hold t.mu.
+func (t *recordingTransport) redactJSON(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if s, ok := child.(string); ok && secretFields[key] {
+				t.addSecret(s)
+				node[key] = redacted
+				continue
+			}
+			node[key] = t.redactJSON(child)
+		}
+	case []interface{}:
+		for i, child := range node {
+			node[i] = t.redactJSON(child)
+		}
+	}
+	return v
+}
+
+// write saves all entries, scrubbing every secret seen so far. The caller must
+// hold t.mu, except during construction.
+func (t *recordingTransport) write() error {
+	entries := make([]harEntry, len(t.entries))
+	copy(entries, t.entries)
+
+	var pairs []string
+	for s := range t.secrets {
+		pairs = append(pairs, s, redacted)
+	}
+	scrub := strings.NewReplacer(pairs...)
+
+	for i := range entries {
+		e := &entries[i]
+		e.Request.URL = scrub.Replace(e.Request.URL)
+		if e.Request.PostData != nil {
+			post := *e.Request.PostData
+			post.Text = scrub.Replace(post.Text)
+			e.Request.PostData = &post
+		}
+		e.Response.Content.Text = scrubContent(scrub, e.Response.Content)
+		e.Request.Headers = scrubHeaders(scrub, e.Request.Headers)
+		e.Response.Headers = scrubHeaders(scrub, e.Response.Headers)
+	}
+
+	// Leave <, > and & unescaped so recordings diff and read cleanly
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "alexacli", Version: "1"},
+		Entries: entries,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal recording: %w", err)
+	}
+
+	if err := os.WriteFile(t.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// scrubContent removes secrets from a response body, decoding base64 bodies first
+func scrubContent(scrub *strings.Replacer, c harContent) string {
+	if c.Encoding != "base64" {
+		return scrub.Replace(c.Text)
+	}
+	raw, err := base64.StdEncoding.DecodeString(c.Text)
+	if err != nil {
+		// Only ever produced by us, so this can't happen; never write it unscrubbed
+		return ""
+	}
+	// Replacer works on bytes, so this is safe for bodies that aren't valid UTF-8
+	return base64.StdEncoding.EncodeToString([]byte(scrub.Replace(string(raw))))
+}
+
+// decompress undoes a Content-Encoding of gzip or deflate
+func decompress(encoding string, body []byte) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "deflate":
+		// Meant to be zlib-wrapped, but some servers send raw deflate
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			r = flate.NewReader(bytes.NewReader(body))
+		} else {
+			r = zr
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return io.ReadAll(r)
+}
+
+func scrubHeaders(scrub *strings.Replacer, headers []harHeader) []harHeader {
+	out := make([]harHeader, len(headers))
+	for i, h := range headers {
+		out[i] = harHeader{Name: h.Name, Value: scrub.Replace(h.Value)}
+	}
+	return out
+}
+
+// replayTransport answers requests from a HAR recording instead of the network.
+// Each request is matched to the first unused entry with the same method and
+// path; hosts and query strings are ignored since they vary between accounts
+// and runs (e.g. history time ranges).
+type replayTransport struct {
+	mu      sync.Mutex
+	entries []harEntry
+	used    []bool
+}
+
+// newReplayTransport loads a recording made with Options.RecordPath
+func newReplayTransport(path string) (*replayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var h har
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+
+	return &replayTransport{
+		entries: h.Log.Entries,
+		used:    make([]bool, len(h.Log.Entries)),
+	}, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, e := range t.entries {
+		if t.used[i] || e.Request.Method != req.Method {
+			continue
+		}
+		recorded, err := url.Parse(e.Request.URL)
+		if err != nil || recorded.Path != req.URL.Path {
+			continue
+		}
+		t.used[i] = true
+
+		body := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text)
+			if err != nil {
+				return nil, fmt.Errorf("corrupt recorded body for %s %s: %w", req.Method, req.URL.Path, err)
+			}
+		}
+
+		header := make(http.Header)
+		for _, h := range e.Response.Headers {
+			header.Add(h.Name, h.Value)
+		}
+		// Redaction may have changed the body length
+		header.Del("Content-Length")
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText),
+			StatusCode:    e.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded response left for %s %s", req.Method, req.URL.Path)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingScrubsEncodedBodies(t *testing.T) {
+	const secret = "Atzr|test-refresh-token-secret"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(`{"echo":"` + secret + `"}`))
+			zw.Close()
+		case "/binary":
+			w.Write(append([]byte{0xff, 0xfe, 0x00}, secret...))
+		case "/brotli":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte{0x1b, 0x2c, 0x00})
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "rec.har")
+	rt, err := newRecordingTransport(http.DefaultTransport, path, slog.New(slog.DiscardHandler), secret)
+	if err != nil {
+		t.Fatalf("newRecordingTransport: %v", err)
+	}
+	client := &http.Client{Transport: rt}
+
+	for _, p := range []string{"/gzip", "/binary", "/brotli"} {
+		req, _ := http.NewRequest("GET", srv.URL+p, nil)
+		// Set by hand, as for AVS, so the transport doesn't decompress for us
+		req.Header.Set("Accept-Encoding", "gzip, br")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", p, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h har
+	if err := json.Unmarshal(data, &h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(h.Log.Entries))
+	}
+
+	for _, e := range h.Log.Entries {
+		body := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+				t.Fatalf("%s: %v", e.Request.URL, err)
+			}
+		}
+		if bytes.Contains(body, []byte(secret)) {
+			t.Errorf("%s: recorded body contains the secret", e.Request.URL)
+		}
+		for _, hdr := range e.Response.Headers {
+			if strings.EqualFold(hdr.Name, "Content-Encoding") {
+				t.Errorf("%s: Content-Encoding kept for a body stored decoded", e.Request.URL)
+			}
+		}
+	}
+
+	if got := h.Log.Entries[0].Response.Content.Text; got != `{"echo":"REDACTED"}` {
+		t.Errorf("gzip body = %s, want it decompressed and redacted", got)
+	}
+	if c := h.Log.Entries[2].Response.Content; c.Text != "" || c.Comment == "" {
+		t.Errorf("brotli body = %q (comment %q), want it left out with a comment", c.Text, c.Comment)
+	}
+}
@@ -0,0 +1,235 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+	"github.com/buddyh/alexa-cli/internal/fake"
+)
+
+func TestSetRoutineEnabledKeepsAutomation(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	// An automation made in the app, with a field this package doesn't model
+	automation := `{
+		"name": "Morning",
+		"status": "ENABLED",
+		"triggers": [],
+		"sequence": {"@type": "com.amazon.alexa.behaviors.model.Sequence", "startNode": null},
+		"tags": ["kitchen", "weekday"]
+	}`
+	if _, err := client.Raw(ctx, api.HostAlexa, "POST", "/api/behaviors/automations", []byte(automation)); err != nil {
+		t.Fatalf("creating automation: %v", err)
+	}
+
+	routines, err := client.GetRoutines(ctx)
+	if err != nil {
+		t.Fatalf("GetRoutines: %v", err)
+	}
+	routine, err := api.MatchRoutine(routines, "morning")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetRoutineEnabled(ctx, routine, false); err != nil {
+		t.Fatalf("SetRoutineEnabled: %v", err)
+	}
+
+	stored := srv.Routines()[0]
+	if stored.Status != "DISABLED" {
+		t.Errorf("status = %s, want DISABLED", stored.Status)
+	}
+	var tags []string
+	if err := json.Unmarshal(stored.Extra["tags"], &tags); err != nil || len(tags) != 2 {
+		t.Errorf("tags = %s, want them kept", stored.Extra["tags"])
+	}
+	if stored.Name != "Morning" || string(stored.Sequence) == "" {
+		t.Errorf("name or sequence lost: %+v", stored)
+	}
+}
+
+func TestExportImportRemapsDevices(t *testing.T) {
+	ctx := context.Background()
+
+	// Export from one account...
+	src, srcSrv := newTestClient(t)
+	kitchen := srcSrv.AddDevice("Kitchen Echo", "ECHO")
+	garage := srcSrv.AddDevice("Garage", "ECHO")
+	automation := `{
+		"name": "Dinner",
+		"status": "ENABLED",
+		"triggers": [],
+		"sequence": {
+			"@type": "com.amazon.alexa.behaviors.model.Sequence",
+			"startNode": {
+				"@type": "com.amazon.alexa.behaviors.model.ParallelNode",
+				"nodesToExecute": [
+					{"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode", "type": "Alexa.Speak",
+					 "operationPayload": {"deviceSerialNumber": "` + kitchen.SerialNumber + `", "deviceType": "A3FAKEECHO", "customerId": "AOLDCUSTOMER", "textToSpeak": "Dinner"}},
+					{"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode", "type": "Alexa.Speak",
+					 "operationPayload": {"deviceSerialNumber": "` + garage.SerialNumber + `", "deviceType": "A3FAKEECHO", "customerId": "AOLDCUSTOMER", "textToSpeak": "Dinner"}}
+				]
+			}
+		},
+		"tags": ["meals"]
+	}`
+	if _, err := src.Raw(ctx, api.HostAlexa, "POST", "/api/behaviors/automations", []byte(automation)); err != nil {
+		t.Fatalf("creating automation: %v", err)
+	}
+	routines, err := src.GetRoutines(ctx)
+	if err != nil {
+		t.Fatalf("GetRoutines: %v", err)
+	}
+	devices, err := src.GetDevices(ctx)
+	if err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	exp, err := api.ExportRoutine(routines[0], devices)
+	if err != nil {
+		t.Fatalf("ExportRoutine: %v", err)
+	}
+	if len(exp.Devices) != 2 {
+		t.Fatalf("exported %d devices, want 2", len(exp.Devices))
+	}
+
+	file, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded api.RoutineExport
+	if err := json.Unmarshal(file, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	// ...and import into another, where the kitchen Echo has a new serial and there is no garage
+	dst, dstSrv := newTestClient(t)
+	dstSrv.AddDevice("Office", "ECHO")
+	dstSrv.AddDevice("Bedroom", "ECHO")
+	newKitchen := dstSrv.AddDevice("kitchen echo", "ECHO")
+	devices, err = dst.GetDevices(ctx)
+	if err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	for _, d := range devices {
+		if d.SerialNumber == garage.SerialNumber {
+			t.Fatalf("garage serial %s belongs to %s on the new account", garage.SerialNumber, d.AccountName)
+		}
+	}
+
+	routine, unmapped, err := dst.RemapRoutine(&loaded, devices)
+	if err != nil {
+		t.Fatalf("RemapRoutine: %v", err)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "'Garage' ("+garage.SerialNumber+")" {
+		t.Errorf("unmapped = %q, want just the garage", unmapped)
+	}
+	if routine.AutomationID != "" {
+		t.Errorf("remapped routine kept the exported automation ID %s", routine.AutomationID)
+	}
+	if _, err := dst.CreateRoutine(ctx, routine); err != nil {
+		t.Fatalf("CreateRoutine: %v", err)
+	}
+
+	stored := dstSrv.Routines()[0]
+	if _, ok := stored.Extra["tags"]; !ok {
+		t.Errorf("tags were not restored")
+	}
+
+	var seq struct {
+		StartNode struct {
+			NodesToExecute []struct {
+				Payload map[string]string `json:"operationPayload"`
+			} `json:"nodesToExecute"`
+		} `json:"startNode"`
+	}
+	if err := json.Unmarshal(stored.Sequence, &seq); err != nil {
+		t.Fatal(err)
+	}
+	nodes := seq.StartNode.NodesToExecute
+	if len(nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(nodes))
+	}
+	if got := nodes[0].Payload["deviceSerialNumber"]; got != newKitchen.SerialNumber {
+		t.Errorf("kitchen serial = %s, want %s", got, newKitchen.SerialNumber)
+	}
+	if got := nodes[1].Payload["deviceSerialNumber"]; got != garage.SerialNumber {
+		t.Errorf("garage serial = %s, want it left as %s", got, garage.SerialNumber)
+	}
+	for i, n := range nodes {
+		if n.Payload["customerId"] == "AOLDCUSTOMER" {
+			t.Errorf("node %d still has the old customer ID", i)
+		}
+	}
+}
+
+func TestRemapRoutineFallsBackToSerial(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newTestClient(t)
+	kitchen := srv.AddDevice("Kitchen Echo", "ECHO")
+	garage := srv.AddDevice("Garage", "ECHO")
+	automation := `{
+		"name": "Dinner",
+		"status": "ENABLED",
+		"triggers": [],
+		"sequence": {
+			"@type": "com.amazon.alexa.behaviors.model.Sequence",
+			"startNode": {
+				"@type": "com.amazon.alexa.behaviors.model.ParallelNode",
+				"nodesToExecute": [
+					{"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode", "type": "Alexa.Speak",
+					 "operationPayload": {"deviceSerialNumber": "` + kitchen.SerialNumber + `", "deviceType": "A3FAKEECHO", "customerId": "` + fake.CustomerID + `", "textToSpeak": "Dinner"}},
+					{"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode", "type": "Alexa.Speak",
+					 "operationPayload": {"deviceSerialNumber": "` + garage.SerialNumber + `", "deviceType": "A3FAKEECHO", "customerId": "` + fake.CustomerID + `", "textToSpeak": "Dinner"}}
+				]
+			}
+		}
+	}`
+	if _, err := client.Raw(ctx, api.HostAlexa, "POST", "/api/behaviors/automations", []byte(automation)); err != nil {
+		t.Fatalf("creating automation: %v", err)
+	}
+	routines, err := client.GetRoutines(ctx)
+	if err != nil {
+		t.Fatalf("GetRoutines: %v", err)
+	}
+
+	// Exported without the device list, so neither device has a name
+	exp, err := api.ExportRoutine(routines[0], nil)
+	if err != nil {
+		t.Fatalf("ExportRoutine: %v", err)
+	}
+	for _, d := range exp.Devices {
+		if d.Name != "" {
+			t.Fatalf("exported device %s has name %q, want none", d.SerialNumber, d.Name)
+		}
+	}
+	// ...and as if the kitchen Echo had since been renamed
+	for i := range exp.Devices {
+		if exp.Devices[i].SerialNumber == kitchen.SerialNumber {
+			exp.Devices[i].Name = "Old Kitchen"
+		}
+	}
+
+	devices, err := client.GetDevices(ctx)
+	if err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	_, unmapped, err := client.RemapRoutine(exp, devices)
+	if err != nil {
+		t.Fatalf("RemapRoutine: %v", err)
+	}
+	if len(unmapped) != 0 {
+		t.Errorf("unmapped = %q, want every device matched by serial", unmapped)
+	}
+
+	// A serial that isn't on the account is still reported
+	exp.Devices = append(exp.Devices, api.ExportedDevice{SerialNumber: "GONE0001"})
+	if _, unmapped, err = client.RemapRoutine(exp, devices); err != nil {
+		t.Fatalf("RemapRoutine: %v", err)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "unnamed device GONE0001" {
+		t.Errorf("unmapped = %q, want just GONE0001", unmapped)
+	}
+}
@@ -0,0 +1,492 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Behavior model types used in sequenceJson
+const (
+	sequenceType      = "com.amazon.alexa.behaviors.model.Sequence"
+	operationNodeType = "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode"
+	serialNodeType    = "com.amazon.alexa.behaviors.model.SerialNode"
+	parallelNodeType  = "com.amazon.alexa.behaviors.model.ParallelNode"
+)
+
+// Node is one step of a behavior sequence: an OperationNode, SerialNode or ParallelNode
+type Node interface {
+	json.Marshaler
+	sequenceNode()
+}
+
+// Sequence is a behavior sequence as sent to /api/behaviors/preview
+type Sequence struct {
+	Start Node
+}
+
+// NewSequence returns a sequence that runs nodes one after another
+func NewSequence(nodes ...Node) Sequence {
+	if len(nodes) == 1 {
+		return Sequence{Start: nodes[0]}
+	}
+	return Sequence{Start: SerialNode(nodes)}
+}
+
+func (s Sequence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string `json:"@type"`
+		StartNode Node   `json:"startNode"`
+	}{sequenceType, s.Start})
+}
+
+// OperationNode runs a single operation, such as Alexa.Speak
+type OperationNode struct {
+	Type    string      // Operation type, e.g. "Alexa.Speak"
+	SkillID string      // Skill that handles the operation, if any
+	Payload interface{} // Marshalled as operationPayload
+}
+
+func (OperationNode) sequenceNode() {}
+
+func (n OperationNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string      `json:"@type"`
+		OpType  string      `json:"type"`
+		SkillID string      `json:"skillId,omitempty"`
+		Payload interface{} `json:"operationPayload"`
+	}{operationNodeType, n.Type, n.SkillID, n.Payload})
+}
+
+// SerialNode runs its nodes one after another
+type SerialNode []Node
+
+func (SerialNode) sequenceNode() {}
+
+func (n SerialNode) MarshalJSON() ([]byte, error) {
+	return marshalNodes(serialNodeType, n)
+}
+
+// ParallelNode runs its nodes at the same time
+type ParallelNode []Node
+
+func (ParallelNode) sequenceNode() {}
+
+func (n ParallelNode) MarshalJSON() ([]byte, error) {
+	return marshalNodes(parallelNodeType, n)
+}
+
+func marshalNodes(nodeType string, nodes []Node) ([]byte, error) {
+	if nodes == nil {
+		nodes = []Node{}
+	}
+	return json.Marshal(struct {
+		Type  string `json:"@type"`
+		Nodes []Node `json:"nodesToExecute"`
+	}{nodeType, nodes})
+}
+
+// DeviceTarget identifies the device an operation runs on
+type DeviceTarget struct {
+	DeviceType         string `json:"deviceType"`
+	DeviceSerialNumber string `json:"deviceSerialNumber"`
+	CustomerID         string `json:"customerId"`
+	Locale             string `json:"locale"`
+}
+
+// SpeakPayload is the payload of an Alexa.Speak operation
+type SpeakPayload struct {
+	DeviceTarget
+	TextToSpeak string `json:"textToSpeak"`
+}
+
+// TextCommandPayload is the payload of an Alexa.TextCommand operation
+type TextCommandPayload struct {
+	DeviceTarget
+	Text string `json:"text"`
+}
+
+// VolumePayload is the payload of an Alexa.DeviceControls.Volume operation
+type VolumePayload struct {
+	DeviceTarget
+	Value int `json:"value"` // 0-100
+}
+
+// MusicSearchPayload is the payload of an Alexa.Music.PlaySearchPhrase operation
+type MusicSearchPayload struct {
+	DeviceTarget
+	SearchPhrase          string `json:"searchPhrase"`
+	SanitizedSearchPhrase string `json:"sanitizedSearchPhrase"`
+	MusicProviderID       string `json:"musicProviderId"`
+}
+
+// WaitPayload is the payload of an Alexa.System.Wait operation
+type WaitPayload struct {
+	WaitTimeInSeconds int `json:"waitTimeInSeconds"`
+}
+
+// AnnouncementPayload is the payload of an AlexaAnnouncement operation
+type AnnouncementPayload struct {
+	ExpireAfter string                `json:"expireAfter"`
+	Content     []AnnouncementContent `json:"content"`
+	Target      AnnouncementTarget    `json:"target"`
+}
+
+// AnnouncementContent is what an announcement shows and says in one locale
+type AnnouncementContent struct {
+	Locale  string `json:"locale"`
+	Display struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	} `json:"display"`
+	Speak struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"speak"`
+}
+
+// AnnouncementTarget selects who hears an announcement: the listed devices,
+// or every device on the account if there are none
+type AnnouncementTarget struct {
+	CustomerID string               `json:"customerId"`
+	Devices    []AnnouncementDevice `json:"devices,omitempty"`
+}
+
+// AnnouncementDevice is one device an announcement is targeted at
+type AnnouncementDevice struct {
+	DeviceSerialNumber string `json:"deviceSerialNumber"`
+	DeviceTypeID       string `json:"deviceTypeId"`
+}
+
+// PushPayload is the payload of an Alexa.Notifications.SendMobilePush operation
+type PushPayload struct {
+	Title               string `json:"title"`
+	NotificationMessage string `json:"notificationMessage"`
+	AlexaURL            string `json:"alexaUrl"`
+	CustomerID          string `json:"customerId"`
+}
+
+// AnnouncementOptions customises an announcement. Zero values use the defaults.
+type AnnouncementOptions struct {
+	Title       string        // Shown on devices with a screen; defaults to "Announcement"
+	ExpireAfter time.Duration // How long the announcement may wait to play; defaults to 5s
+	Devices     []*Device     // Devices to announce on; empty means the whole household
+}
+
+// deviceTarget returns the target fields for operations on device
+func (c *Client) deviceTarget(device *Device) DeviceTarget {
+	return DeviceTarget{
+		DeviceType:         device.DeviceType,
+		DeviceSerialNumber: device.SerialNumber,
+		CustomerID:         c.customerIDFor(device),
+		Locale:             c.locale(),
+	}
+}
+
+// SpeakOperation returns a node that makes device say text
+func (c *Client) SpeakOperation(device *Device, text string) OperationNode {
+	return OperationNode{
+		Type:    "Alexa.Speak",
+		Payload: SpeakPayload{DeviceTarget: c.deviceTarget(device), TextToSpeak: text},
+	}
+}
+
+// TextCommandOperation returns a node that runs text on device as if it were spoken
+func (c *Client) TextCommandOperation(device *Device, text string) OperationNode {
+	return OperationNode{
+		Type:    "Alexa.TextCommand",
+		SkillID: "amzn1.ask.1p.tellalexa",
+		Payload: TextCommandPayload{DeviceTarget: c.deviceTarget(device), Text: text},
+	}
+}
+
+// AnnouncementOperation returns a node that announces text. device supplies the
+// customer ID if it isn't known yet.
+func (c *Client) AnnouncementOperation(device *Device, text string, opts AnnouncementOptions) OperationNode {
+	if opts.Title == "" {
+		opts.Title = "Announcement"
+	}
+	if opts.ExpireAfter <= 0 {
+		opts.ExpireAfter = 5 * time.Second
+	}
+
+	content := AnnouncementContent{Locale: c.locale()}
+	content.Display.Title = opts.Title
+	content.Display.Body = text
+	content.Speak.Type = "text"
+	content.Speak.Value = text
+
+	target := AnnouncementTarget{CustomerID: c.customerIDFor(device)}
+	for _, d := range opts.Devices {
+		target.Devices = append(target.Devices, AnnouncementDevice{
+			DeviceSerialNumber: d.SerialNumber,
+			DeviceTypeID:       d.DeviceType,
+		})
+	}
+
+	return OperationNode{
+		Type: "AlexaAnnouncement",
+		Payload: AnnouncementPayload{
+			// ISO 8601 duration in whole seconds, e.g. PT5S
+			ExpireAfter: fmt.Sprintf("PT%dS", int(math.Ceil(opts.ExpireAfter.Seconds()))),
+			Content:     []AnnouncementContent{content},
+			Target:      target,
+		},
+	}
+}
+
+// PushOperation returns a node that sends a notification to the Alexa app on
+// the account's phones. device supplies the customer ID if it isn't known yet.
+func (c *Client) PushOperation(device *Device, title, message string) OperationNode {
+	if title == "" {
+		title = "alexacli"
+	}
+	return OperationNode{
+		Type:    "Alexa.Notifications.SendMobilePush",
+		SkillID: "amzn1.ask.1p.routines.messaging",
+		Payload: PushPayload{
+			Title:               title,
+			NotificationMessage: message,
+			AlexaURL:            "#v2/behaviors",
+			CustomerID:          c.customerIDFor(device),
+		},
+	}
+}
+
+// VolumeOperation returns a node that sets device's volume to level (0-100)
+func (c *Client) VolumeOperation(device *Device, level int) OperationNode {
+	return OperationNode{
+		Type:    "Alexa.DeviceControls.Volume",
+		Payload: VolumePayload{DeviceTarget: c.deviceTarget(device), Value: level},
+	}
+}
+
+// musicProviders maps the provider names users type to Amazon's provider IDs
+var musicProviders = map[string]string{
+	"amazon":  "AMAZON_MUSIC",
+	"spotify": "SPOTIFY",
+	"tunein":  "TUNEIN",
+	"apple":   "APPLE_MUSIC",
+	"deezer":  "DEEZER",
+	"iheart":  "I_HEART_RADIO",
+	"library": "CLOUDPLAYER",
+}
+
+// MusicProviderID returns Amazon's ID for a music provider name such as "spotify".
+// Amazon IDs such as "SPOTIFY" are accepted as they are.
+func MusicProviderID(name string) (string, error) {
+	if id, ok := musicProviders[strings.ToLower(name)]; ok {
+		return id, nil
+	}
+	for _, id := range musicProviders {
+		if strings.EqualFold(name, id) {
+			return id, nil
+		}
+	}
+
+	names := make([]string, 0, len(musicProviders))
+	for n := range musicProviders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown music provider %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// PlayMusicOperation returns a node that searches providerID (see MusicProviderID)
+// for phrase and plays the result on device, which may be a multi-room group
+func (c *Client) PlayMusicOperation(device *Device, providerID, phrase string) OperationNode {
+	return OperationNode{
+		Type: "Alexa.Music.PlaySearchPhrase",
+		Payload: MusicSearchPayload{
+			DeviceTarget:          c.deviceTarget(device),
+			SearchPhrase:          phrase,
+			SanitizedSearchPhrase: strings.ToLower(strings.TrimSpace(phrase)),
+			MusicProviderID:       providerID,
+		},
+	}
+}
+
+// WaitOperation returns a node that pauses the sequence. Alexa waits in whole
+// seconds, so d is rounded up.
+func WaitOperation(d time.Duration) OperationNode {
+	return OperationNode{
+		Type:    "Alexa.System.Wait",
+		Payload: WaitPayload{WaitTimeInSeconds: int(math.Ceil(d.Seconds()))},
+	}
+}
+
+// builtinSteps maps step kinds to the built-in operations that take no argument
+var builtinSteps = map[string]string{
+	"weather":       OpWeather,
+	"traffic":       OpTraffic,
+	"flashbriefing": OpFlashBriefing,
+	"goodmorning":   OpGoodMorning,
+	"joke":          OpJoke,
+	"funfact":       OpFunFact,
+	"singasong":     OpSingASong,
+}
+
+// ParseStep builds the node for a step written as "<kind>:<argument>", where kind is
+// speak, announcement, textcommand, volume (0-100), wait (a duration such as "5s",
+// or a number of seconds), sound (a sound library ID) or push (a notification to
+// the Alexa app). The built-in operations
+// weather, traffic, flashbriefing, goodmorning, joke, funfact, singasong and stop
+// take no argument and may be written without the colon. Quotes around the
+// argument are ignored.
+func (c *Client) ParseStep(device *Device, step string) (Node, error) {
+	kind, arg, ok := strings.Cut(step, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if op, builtin := builtinSteps[kind]; builtin {
+		return c.DeviceOperation(device, op), nil
+	}
+	if kind == "stop" {
+		return c.StopOperation(device, device), nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid step %q: expected <kind>:<argument>", step)
+	}
+	arg = strings.Trim(arg, "'\"")
+
+	switch kind {
+	case "speak":
+		return c.SpeakOperation(device, arg), nil
+	case "announcement", "announce":
+		return c.AnnouncementOperation(device, arg, AnnouncementOptions{}), nil
+	case "textcommand", "command":
+		return c.TextCommandOperation(device, arg), nil
+	case "volume":
+		level, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || level < 0 || level > 100 {
+			return nil, fmt.Errorf("invalid step %q: volume must be 0-100", step)
+		}
+		return c.VolumeOperation(device, level), nil
+	case "wait":
+		d, err := parseWait(strings.TrimSpace(arg))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid step %q: wait needs a positive duration such as 5s", step)
+		}
+		return WaitOperation(d), nil
+	case "push":
+		return c.PushOperation(device, "", arg), nil
+	case "sound":
+		if strings.TrimSpace(arg) == "" {
+			return nil, fmt.Errorf("invalid step %q: sound needs a sound ID (see 'alexacli sounds list')", step)
+		}
+		return c.SoundOperation(device, strings.TrimSpace(arg)), nil
+	default:
+		return nil, fmt.Errorf("unknown step type %q", kind)
+	}
+}
+
+// parseWait parses a duration, treating a bare number as seconds
+func parseWait(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// RunSequence runs a behavior sequence immediately
+func (c *Client) RunSequence(ctx context.Context, seq Sequence) error {
+	data, err := json.Marshal(seq)
+	if err != nil {
+		return err
+	}
+	return c.runBehavior(ctx, "PREVIEW", string(data))
+}
+
+// RunSequenceJSON runs a complete sequence document, such as one built in the
+// Alexa app, immediately
+func (c *Client) RunSequenceJSON(ctx context.Context, doc []byte) error {
+	var seq struct {
+		Type      string          `json:"@type"`
+		StartNode json.RawMessage `json:"startNode"`
+	}
+	if err := json.Unmarshal(doc, &seq); err != nil {
+		return fmt.Errorf("invalid sequence: %w", err)
+	}
+	if seq.Type != sequenceType {
+		return fmt.Errorf("invalid sequence: @type must be %q, got %q", sequenceType, seq.Type)
+	}
+	if len(seq.StartNode) == 0 || string(seq.StartNode) == "null" {
+		return fmt.Errorf("invalid sequence: missing startNode")
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, doc); err != nil {
+		return fmt.Errorf("invalid sequence: %w", err)
+	}
+	return c.runBehavior(ctx, "PREVIEW", compact.String())
+}
+
+// deviceVarPattern matches placeholders such as ${device.serial}
+var deviceVarPattern = regexp.MustCompile(`\$\{device\.([A-Za-z]+)\}`)
+
+// ExpandDeviceVars fills the placeholders ${device.serial}, ${device.type},
+// ${device.customerId}, ${device.locale} and ${device.name} in a sequence
+// document with device's values, escaped for use inside JSON strings. device
+// may be nil if doc has no placeholders.
+func (c *Client) ExpandDeviceVars(doc []byte, device *Device) ([]byte, error) {
+	var err error
+	expanded := deviceVarPattern.ReplaceAllFunc(doc, func(match []byte) []byte {
+		name := string(deviceVarPattern.FindSubmatch(match)[1])
+		if device == nil {
+			if err == nil {
+				err = fmt.Errorf("sequence uses %s but no device was given", match)
+			}
+			return match
+		}
+
+		var value string
+		switch name {
+		case "serial":
+			value = device.SerialNumber
+		case "type":
+			value = device.DeviceType
+		case "customerId":
+			value = c.customerIDFor(device)
+		case "locale":
+			value = c.locale()
+		case "name":
+			value = device.AccountName
+		default:
+			if err == nil {
+				err = fmt.Errorf("unknown placeholder %s (use serial, type, customerId, locale or name)", match)
+			}
+			return match
+		}
+
+		// Escape as a JSON string, then drop the surrounding quotes
+		quoted, _ := json.Marshal(value)
+		return quoted[1 : len(quoted)-1]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// runBehavior posts a sequence to the behaviors preview endpoint, which runs it immediately
+func (c *Client) runBehavior(ctx context.Context, behaviorID, sequenceJSON string) error {
+	payload := map[string]interface{}{
+		"behaviorId":   behaviorID,
+		"sequenceJson": sequenceJSON,
+		"status":       "ENABLED",
+	}
+
+	planned := newPlannedRequest("POST", c.baseURL()+"/api/behaviors/preview", payload)
+	planned.Sequence = json.RawMessage(sequenceJSON)
+	if err := c.checkDryRun(planned); err != nil {
+		return err
+	}
+
+	_, err := c.request(ctx, "POST", "/api/behaviors/preview", payload)
+	return err
+}
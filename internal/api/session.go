@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// sessionTTL is how long cached session cookies are trusted before re-authenticating
+	sessionTTL = 24 * time.Hour
+
+	// sessionLockTimeout is how long to wait for another process holding the session lock
+	sessionLockTimeout = 15 * time.Second
+
+	// sessionLockStale is the age after which a leftover lock file is considered abandoned
+	sessionLockStale = 60 * time.Second
+)
+
+// session is the authenticated client state persisted between CLI invocations
+type session struct {
+	Domain       string    `json:"domain"`
+	TokenHash    string    `json:"token_hash"` // ties the session to the refresh token that created it
+	Endpoints    Endpoints `json:"endpoints"`  // hosts the cookies were issued by
+	Cookies      string    `json:"cookies"`
+	CSRF         string    `json:"csrf"`
+	ActivityCSRF string    `json:"activity_csrf,omitempty"`
+	BearerToken  string    `json:"bearer_token,omitempty"`
+	BearerExpiry time.Time `json:"bearer_expiry,omitempty"`
+	CustomerID   string    `json:"customer_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// hashToken returns a short fingerprint of a refresh token so the token itself is never stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// loadSession restores cached state from the session file.
+// Returns false if there is no usable session (missing, expired, or for another account).
+// The caller must hold the session lock.
+func (c *Client) loadSession() bool {
+	data, err := os.ReadFile(c.sessionPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Debug("failed to read session file", "error", err)
+		}
+		return false
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		c.logger.Debug("ignoring unreadable session file", "error", err)
+		return false
+	}
+
+	if s.Domain != c.amazonDomain || s.TokenHash != hashToken(c.refreshToken) || s.Endpoints != c.endpoints {
+		c.logger.Debug("cached session belongs to a different account or endpoints, re-authenticating")
+		return false
+	}
+	if s.Cookies == "" || s.CSRF == "" || time.Now().After(s.ExpiresAt) {
+		c.logger.Debug("cached session expired, re-authenticating")
+		return false
+	}
+
+	c.secrets.addCookies(s.Cookies)
+	c.secrets.add(s.CSRF, s.ActivityCSRF, s.BearerToken)
+
+	c.mu.Lock()
+	c.cookies = s.Cookies
+	c.csrf = s.CSRF
+	c.activityCSRF = s.ActivityCSRF
+	c.customerID = s.CustomerID
+	c.sessionExpiry = s.ExpiresAt
+	if time.Now().Before(s.BearerExpiry) {
+		c.bearerToken = s.BearerToken
+		c.bearerExpiry = s.BearerExpiry
+	}
+	c.mu.Unlock()
+
+	c.logger.Debug("using cached session", "expires", s.ExpiresAt)
+	return true
+}
+
+// writeSession writes the current state to the session file.
+// The caller must hold the session lock.
+func (c *Client) writeSession() error {
+	c.mu.Lock()
+	s := session{
+		Domain:       c.amazonDomain,
+		TokenHash:    hashToken(c.refreshToken),
+		Endpoints:    c.endpoints,
+		Cookies:      c.cookies,
+		CSRF:         c.csrf,
+		ActivityCSRF: c.activityCSRF,
+		BearerToken:  c.bearerToken,
+		BearerExpiry: c.bearerExpiry,
+		CustomerID:   c.customerID,
+		ExpiresAt:    c.sessionExpiry,
+	}
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(c.sessionPath), ".session-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set session file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.sessionPath); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return nil
+}
+
+// saveSession persists the current state if a session file is configured.
+// Failures are logged rather than returned since the cache is only an optimisation.
+func (c *Client) saveSession(ctx context.Context) {
+	if c.sessionPath == "" {
+		return
+	}
+
+	unlock, err := lockSession(ctx, c.sessionPath)
+	if err != nil {
+		c.logger.Debug("failed to lock session file", "error", err)
+		return
+	}
+	defer unlock()
+
+	if err := c.writeSession(); err != nil {
+		c.logger.Debug("failed to save session", "error", err)
+	}
+}
+
+// lockSession takes an exclusive lock on the session file, waiting for other processes if needed.
+// A lock file is used rather than flock so the same code works on every platform.
+func lockSession(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(sessionLockTimeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create session lock: %w", err)
+		}
+
+		// Break locks left behind by a crashed process
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > sessionLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for session lock %s", lockPath)
+		}
+		if err := SleepContext(ctx, 50*time.Millisecond); err != nil {
+			return nil, err
+		}
+	}
+}
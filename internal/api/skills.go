@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Built-in Alexa operations that only need the device to run on
+const (
+	OpWeather       = "Alexa.Weather.Play"
+	OpTraffic       = "Alexa.Traffic.Play"
+	OpFlashBriefing = "Alexa.FlashBriefing.Play"
+	OpGoodMorning   = "Alexa.GoodMorning.Play"
+	OpJoke          = "Alexa.Joke.Play"
+	OpFunFact       = "Alexa.FunFact.Play"
+	OpSingASong     = "Alexa.SingASong.Play"
+)
+
+// soundSkillID is the skill that owns the Alexa sound library
+const soundSkillID = "amzn1.ask.1p.sound"
+
+// DeviceOperation returns a node running a built-in operation such as OpWeather on device
+func (c *Client) DeviceOperation(device *Device, opType string) OperationNode {
+	return OperationNode{Type: opType, Payload: c.deviceTarget(device)}
+}
+
+// StopPayload is the payload of an Alexa.DeviceControls.Stop operation
+type StopPayload struct {
+	Devices            []StopDevice `json:"devices"`
+	CustomerID         string       `json:"customerId"`
+	IsAssociatedDevice bool         `json:"isAssociatedDevice"`
+}
+
+// StopDevice is one device a Stop operation applies to
+type StopDevice struct {
+	DeviceSerialNumber string `json:"deviceSerialNumber"`
+	DeviceType         string `json:"deviceType"`
+}
+
+// StopOperation returns a node that stops whatever is playing on devices, or on
+// every device if none are given. device supplies the customer ID if it isn't known yet.
+func (c *Client) StopOperation(device *Device, devices ...*Device) OperationNode {
+	payload := StopPayload{CustomerID: c.customerIDFor(device)}
+	for _, d := range devices {
+		payload.Devices = append(payload.Devices, StopDevice{
+			DeviceSerialNumber: d.SerialNumber,
+			DeviceType:         d.DeviceType,
+		})
+	}
+	if len(payload.Devices) == 0 {
+		// Amazon's placeholder for the whole household
+		payload.Devices = []StopDevice{{DeviceSerialNumber: "ALEXA_ALL_DSN", DeviceType: "ALEXA_ALL_DEVICE_TYPE"}}
+	}
+
+	return OperationNode{Type: "Alexa.DeviceControls.Stop", Payload: payload}
+}
+
+// SoundPayload is the payload of an Alexa.Sound operation
+type SoundPayload struct {
+	DeviceTarget
+	SoundStringID string `json:"soundStringId"`
+}
+
+// SoundOperation returns a node that plays a sound library sound, such as
+// "amzn_sfx_doorbell_chime_01", on device
+func (c *Client) SoundOperation(device *Device, soundID string) OperationNode {
+	return OperationNode{
+		Type:    "Alexa.Sound",
+		SkillID: soundSkillID,
+		Payload: SoundPayload{DeviceTarget: c.deviceTarget(device), SoundStringID: soundID},
+	}
+}
+
+// Sound is an entry in the Alexa sound library
+type Sound struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// GetSounds returns the sounds in the Alexa sound library
+func (c *Client) GetSounds(ctx context.Context) ([]Sound, error) {
+	data, err := c.request(ctx, "GET", "/api/behaviors/entities?skillId="+url.QueryEscape(soundSkillID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sounds []Sound
+	if err := json.Unmarshal(data, &sounds); err != nil {
+		return nil, fmt.Errorf("failed to parse sounds: %w", err)
+	}
+
+	return sounds, nil
+}
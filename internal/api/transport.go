@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail transiently are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retrying.
+	MaxRetries int
+
+	// BaseDelay is the backoff before the first retry. It doubles on each further retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps a single backoff, including delays requested with Retry-After
+	MaxDelay time.Duration
+}
+
+// RateLimit caps the rate of outgoing requests with a token bucket
+type RateLimit struct {
+	// PerSecond is the sustained number of requests allowed per second. Zero disables limiting.
+	PerSecond float64
+
+	// Burst is how many requests may be sent back to back before PerSecond applies
+	Burst int
+}
+
+var (
+	// DefaultRetryPolicy is used when Options.Retry is nil
+	DefaultRetryPolicy = RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+
+	// DefaultRateLimit is used when Options.RateLimit is nil. It stays below
+	// the rate at which Amazon starts throttling /api/behaviors/preview.
+	DefaultRateLimit = RateLimit{
+		PerSecond: 2,
+		Burst:     5,
+	}
+)
+
+// do sends a request through the shared retry and rate-limit layer.
+// 429 responses and failed connections are retried with jittered exponential
+// backoff, honouring Retry-After. Other network errors and 5xx responses are only
+// retried for idempotent methods (see shouldRetry). Request bodies are replayed
+// with req.GetBody, which http.NewRequest sets for the bytes and strings readers
+// used in this package.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		try := req
+		if attempt > 0 {
+			try = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				try.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(try)
+
+		attrs := []any{
+			"method", req.Method,
+			"host", req.URL.Host,
+			"endpoint", req.URL.Path,
+			"latency", time.Since(start),
+			"retries", attempt,
+		}
+		if err != nil {
+			c.logger.Debug("http request failed", append(attrs, "error", err)...)
+		} else {
+			c.logger.Debug("http request", append(attrs, "status", resp.StatusCode)...)
+		}
+
+		if attempt >= c.retry.MaxRetries || !shouldRetry(ctx, req, resp, err) {
+			return resp, err
+		}
+
+		delay := c.retry.backoff(attempt)
+		if err == nil {
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+				if c.retry.MaxDelay > 0 {
+					delay = min(delay, c.retry.MaxDelay)
+				}
+			}
+
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.logger.Debug("retrying request", "method", req.Method, "endpoint", req.URL.Path, "retry", attempt+1, "delay", delay)
+
+		if err := SleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating
+func shouldRetry(ctx context.Context, req *http.Request, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// A body we cannot rebuild cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		// A POST may have been acted on before the connection failed, and sending
+		// it again would repeat a speak or create a second routine. Only retry it
+		// if the connection was never made.
+		return isIdempotent(req.Method) || neverSent(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// Throttled requests are rejected before they are acted on
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+// isIdempotent reports whether sending a request with method twice has the same effect as sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// neverSent reports whether err means the request could not reach the server,
+// e.g. a DNS failure or a refused connection
+func neverSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// backoff returns the jittered delay before retry number attempt+1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay < p.BaseDelay || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+
+	// Pick a delay in [delay/2, delay) so parallel clients spread out
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+// tokenBucket is a client-side rate limiter shared by every request a Client sends
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // bucket capacity
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a limiter for the given rate, or nil if limiting is disabled
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.PerSecond <= 0 {
+		return nil
+	}
+
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{
+		rate:   limit.PerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a request may be sent. A nil bucket never blocks.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	// Take a token now, possibly going into debt, and sleep until it would have been available.
+	// Reserving up front keeps waiters in order without holding the lock while sleeping.
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	if err := SleepContext(ctx, delay); err != nil {
+		// Give the token back so a cancelled caller does not slow down the others
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
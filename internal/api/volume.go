@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DeviceVolume is the current volume of an Echo device
+type DeviceVolume struct {
+	SerialNumber  string `json:"dsn"`
+	DeviceType    string `json:"deviceType"`
+	SpeakerVolume int    `json:"speakerVolume"` // 0-100
+	SpeakerMuted  bool   `json:"speakerMuted"`
+}
+
+// GetVolumes returns the current volume of every device on the account
+func (c *Client) GetVolumes(ctx context.Context) ([]DeviceVolume, error) {
+	data, err := c.request(ctx, "GET", "/api/devices/deviceType/dsn/audio/v1/allDeviceVolumes", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Volumes []DeviceVolume `json:"volumes"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse volumes: %w", err)
+	}
+
+	return result.Volumes, nil
+}
+
+// GetVolume returns the current volume of a device
+func (c *Client) GetVolume(ctx context.Context, device *Device) (*DeviceVolume, error) {
+	volumes, err := c.GetVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range volumes {
+		if v.SerialNumber == device.SerialNumber {
+			return &volumes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no volume reported for %s", device.AccountName)
+}
+
+// SetVolume sets a device's volume (0-100)
+func (c *Client) SetVolume(ctx context.Context, device *Device, level int) error {
+	if level < 0 || level > 100 {
+		return fmt.Errorf("volume must be 0-100, got %d", level)
+	}
+	return c.RunSequence(ctx, NewSequence(c.VolumeOperation(device, level)))
+}
@@ -5,18 +5,50 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/buddyh/alexa-cli/internal/api"
 )
 
 const (
-	configDirName  = ".alexa-cli"
-	configFileName = "config.json"
+	configDirName   = ".alexa-cli"
+	configFileName  = "config.json"
+	sessionFileName = "session.json"
 )
 
 // Config holds the Alexa CLI configuration
 type Config struct {
-	RefreshToken string `json:"refresh_token"`
-	AmazonDomain string `json:"amazon_domain,omitempty"` // e.g., "amazon.com", "amazon.de"
-	DeviceSerial string `json:"default_device,omitempty"`
+	RefreshToken string    `json:"refresh_token"`
+	AmazonDomain string    `json:"amazon_domain,omitempty"` // e.g., "amazon.com", "amazon.de"
+	DeviceSerial string    `json:"default_device,omitempty"`
+	Endpoints    Endpoints `json:"endpoints,omitzero"`
+
+	// Groups maps a group name to the device names or serials in it
+	Groups map[string][]string `json:"groups,omitempty"`
+}
+
+// Endpoints overrides the base URLs the CLI talks to. Empty fields use Amazon's
+// defaults for the configured domain.
+type Endpoints = api.Endpoints
+
+// endpointEnv maps environment variables to the endpoint they override
+var endpointEnv = []struct {
+	name  string
+	field func(*Endpoints) *string
+}{
+	{"ALEXA_API_URL", func(e *Endpoints) *string { return &e.API }},
+	{"ALEXA_ALEXA_URL", func(e *Endpoints) *string { return &e.Alexa }},
+	{"ALEXA_ACTIVITY_URL", func(e *Endpoints) *string { return &e.Activity }},
+	{"ALEXA_AVS_URL", func(e *Endpoints) *string { return &e.AVS }},
+	{"ALEXA_AUTH_URL", func(e *Endpoints) *string { return &e.Auth }},
+}
+
+// ApplyEnv overrides e with any ALEXA_*_URL environment variables that are set
+func ApplyEnv(e *Endpoints) {
+	for _, env := range endpointEnv {
+		if val := os.Getenv(env.name); val != "" {
+			*env.field(e) = val
+		}
+	}
 }
 
 // Path returns the full path to the config file
@@ -37,14 +69,25 @@ func Dir() (string, error) {
 	return filepath.Join(home, configDirName), nil
 }
 
+// SessionPath returns the full path to the cached session file
+func SessionPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sessionFileName), nil
+}
+
 // Load reads the configuration from disk
 func Load() (*Config, error) {
 	// Check environment variable first
 	if token := os.Getenv("ALEXA_REFRESH_TOKEN"); token != "" {
-		return &Config{
+		cfg := &Config{
 			RefreshToken: token,
 			AmazonDomain: getEnvOrDefault("ALEXA_AMAZON_DOMAIN", "amazon.com"),
-		}, nil
+		}
+		ApplyEnv(&cfg.Endpoints)
+		return cfg, nil
 	}
 
 	path, err := Path()
@@ -74,9 +117,50 @@ func Load() (*Config, error) {
 		cfg.AmazonDomain = "amazon.com"
 	}
 
+	ApplyEnv(&cfg.Endpoints)
+
 	return &cfg, nil
 }
 
+// ReadFile reads the config file without applying environment overrides or
+// defaults. A missing file gives an empty config.
+func ReadFile() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Group returns the devices in a named device group from the config file.
+// Groups are read from the file even when ALEXA_REFRESH_TOKEN is set.
+func Group(name string) ([]string, error) {
+	cfg, err := ReadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	members, ok := cfg.Groups[name]
+	if !ok || len(members) == 0 {
+		path, _ := Path()
+		return nil, fmt.Errorf("unknown device group %q (define it under \"groups\" in %s)", name, path)
+	}
+	return members, nil
+}
+
 // Save writes the configuration to disk
 func Save(cfg *Config) error {
 	dir, err := Dir()
@@ -0,0 +1,223 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// conversation is an Alexa+ conversation thread
+type conversation struct {
+	id         string
+	deviceName string
+	created    time.Time
+	fragments  []fragment
+}
+
+// fragment is one turn in a conversation
+type fragment struct {
+	uri     string
+	purpose string // USER or AGENT
+	text    string
+	time    time.Time
+}
+
+// newConversation starts a conversation. The caller must hold s.mu.
+func (s *Server) newConversation(deviceName string) *conversation {
+	s.idCounter++
+	conv := &conversation{
+		id:         fmt.Sprintf("amzn1.conversation.00000000-fake-4000-8000-%012d", s.idCounter),
+		deviceName: deviceName,
+		created:    time.Now(),
+	}
+	s.conversations = append(s.conversations, conv)
+	return conv
+}
+
+// conversation returns the conversation with an ID, or nil. The caller must hold s.mu.
+func (s *Server) conversation(id string) *conversation {
+	for _, conv := range s.conversations {
+		if conv.id == id {
+			return conv
+		}
+	}
+	return nil
+}
+
+// avsEvent is the metadata part of an AVS events request
+type avsEvent struct {
+	Event struct {
+		Header struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"header"`
+		Payload struct {
+			Text string `json:"text"`
+		} `json:"payload"`
+	} `json:"event"`
+	Context []struct {
+		Header struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"header"`
+		Payload struct {
+			ConversationID string `json:"conversationId"`
+		} `json:"payload"`
+	} `json:"context"`
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	event, err := readAVSEvent(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"message":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	header := event.Event.Header
+	switch {
+	case header.Namespace == "System" && header.Name == "SynchronizeState":
+		w.WriteHeader(http.StatusNoContent)
+
+	case header.Namespace == "Alexa.Input.Text" && header.Name == "TextMessage":
+		convID := ""
+		for _, c := range event.Context {
+			if c.Header.Namespace == "Alexa.Conversation" {
+				convID = c.Payload.ConversationID
+			}
+		}
+
+		s.mu.Lock()
+		conv := s.conversation(convID)
+		if conv == nil {
+			deviceName := "This Device"
+			if len(s.devices) > 0 {
+				deviceName = s.devices[0].AccountName
+			}
+			conv = s.newConversation(deviceName)
+		}
+
+		// The answer is only available by polling fragments, like a slow LLM turn
+		now := time.Now()
+		n := len(conv.fragments)
+		conv.fragments = append(conv.fragments,
+			fragment{uri: fmt.Sprintf("%s/fragments/%d:USER", conv.id, n), purpose: "USER", text: event.Event.Payload.Text, time: now},
+			fragment{uri: fmt.Sprintf("%s/fragments/%d:LLM:APE", conv.id, n+1), purpose: "AGENT", text: s.answer(event.Event.Payload.Text), time: now},
+		)
+		id := conv.id
+		s.mu.Unlock()
+
+		boundary := "fake-avs-boundary"
+		w.Header().Set("Content-Type", "multipart/related; boundary="+boundary+"; type=\"application/json\"")
+		directive, _ := json.Marshal(map[string]interface{}{
+			"directive": map[string]interface{}{
+				"header":  map[string]string{"namespace": "Alexa.Conversation", "name": "AddFragments"},
+				"payload": map[string]string{"conversationId": id},
+			},
+		})
+		fmt.Fprintf(w, "--%s\r\nContent-Type: application/json; charset=UTF-8\r\n\r\n%s\r\n--%s--\r\n", boundary, directive, boundary)
+
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// readAVSEvent decodes the metadata part of a multipart AVS request
+func readAVSEvent(r *http.Request) (*avsEvent, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return nil, fmt.Errorf("expected multipart body")
+	}
+
+	part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+	if err != nil {
+		return nil, fmt.Errorf("malformed multipart body: %w", err)
+	}
+	defer part.Close()
+
+	if part.FormName() != "metadata" {
+		return nil, fmt.Errorf("first part must be metadata, got %q", part.FormName())
+	}
+
+	var event avsEvent
+	if err := json.NewDecoder(part).Decode(&event); err != nil {
+		return nil, fmt.Errorf("malformed event: %w", err)
+	}
+	return &event, nil
+}
+
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type turn struct {
+		Origin struct {
+			Name string `json:"name"`
+		} `json:"origin"`
+		Time string `json:"time"`
+	}
+	type item struct {
+		ID       string `json:"id"`
+		Creation turn   `json:"creation"`
+		LastTurn *turn  `json:"lastTurn,omitempty"`
+	}
+
+	items := []item{}
+	for _, conv := range s.conversations {
+		it := item{ID: conv.id}
+		it.Creation.Origin.Name = conv.deviceName
+		it.Creation.Time = conv.created.UTC().Format(time.RFC3339)
+		if n := len(conv.fragments); n > 0 {
+			last := &turn{Time: conv.fragments[n-1].time.UTC().Format(time.RFC3339)}
+			last.Origin.Name = conv.deviceName
+			it.LastTurn = last
+		}
+		items = append(items, it)
+	}
+
+	writeJSON(w, map[string]interface{}{"conversations": items})
+}
+
+func (s *Server) handleFragments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv := s.conversation(r.PathValue("id"))
+	if conv == nil {
+		http.Error(w, `{"message":"conversation not found"}`, http.StatusNotFound)
+		return
+	}
+
+	type content struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type frag struct {
+		FragmentURI string  `json:"fragmentURI"`
+		Timestamp   string  `json:"timestamp"`
+		Content     content `json:"content"`
+		Metadata    struct {
+			Purpose string `json:"purpose"`
+		} `json:"metadata"`
+	}
+
+	// Newest first, so the latest AGENT turn is the first one a poller sees
+	frags := make([]frag, len(conv.fragments))
+	for i, f := range conv.fragments {
+		i = len(frags) - 1 - i
+		frags[i] = frag{
+			FragmentURI: f.uri,
+			Timestamp:   f.time.UTC().Format(time.RFC3339Nano),
+			Content:     content{Type: "Card", Text: f.text},
+		}
+		frags[i].Metadata.Purpose = f.purpose
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"conversationId": conv.id,
+		"fragments":      frags,
+		"token":          fmt.Sprintf("fake-sync-%d", len(frags)),
+	})
+}
@@ -0,0 +1,94 @@
+package fake
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NewDemoServer starts a fake backend populated with a small household:
+// a few Echo devices, smart home lights, a routine, some voice history,
+// music playing in the kitchen and an Alexa+ conversation per device
+func NewDemoServer() *Server {
+	s := NewServer()
+
+	kitchen := s.AddDevice("Kitchen Echo", "ECHO")
+	s.AddDevice("Living Room Echo Show", "KNIGHT")
+	office := s.AddDevice("Office", "ECHO")
+	s.AddDevice("Everywhere", "WHA") // multi-room music group
+
+	s.Play(kitchen.SerialNumber,
+		Track{Title: "So What", Artist: "Miles Davis", Album: "Kind of Blue", Provider: "Amazon Music", Length: 562 * time.Second},
+		Track{Title: "Freddie Freeloader", Artist: "Miles Davis", Album: "Kind of Blue", Provider: "Amazon Music", Length: 586 * time.Second},
+		Track{Title: "Blue in Green", Artist: "Miles Davis", Album: "Kind of Blue", Provider: "Amazon Music", Length: 337 * time.Second},
+	)
+
+	s.AddSmartHomeDevice("Kitchen Light", "LIGHT")
+	s.AddSmartHomeDevice("Bedroom Lamp", "LIGHT")
+	s.AddSmartHomeDevice("Porch Plug", "SMARTPLUG")
+
+	s.AddRoutine("Good Night", json.RawMessage(`{
+		"@type": "com.amazon.alexa.behaviors.model.Sequence",
+		"startNode": {
+			"@type": "com.amazon.alexa.behaviors.model.SerialNode",
+			"nodesToExecute": [
+				{
+					"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
+					"type": "Alexa.DeviceControls.Volume",
+					"operationPayload": {"deviceSerialNumber": "`+kitchen.SerialNumber+`", "deviceType": "`+kitchen.DeviceType+`", "value": 20}
+				},
+				{
+					"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
+					"type": "Alexa.Speak",
+					"operationPayload": {"deviceSerialNumber": "`+kitchen.SerialNumber+`", "deviceType": "`+kitchen.DeviceType+`", "textToSpeak": "Good night"}
+				},
+				{
+					"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
+					"type": "Alexa.System.Wait",
+					"operationPayload": {"waitTimeInSeconds": 5}
+				},
+				{
+					"@type": "com.amazon.alexa.behaviors.model.ParallelNode",
+					"nodesToExecute": [
+						{
+							"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
+							"type": "Alexa.DeviceControls.Stop",
+							"operationPayload": {"devices": [{"deviceSerialNumber": "ALEXA_ALL_DSN", "deviceType": "ALEXA_ALL_DEVICE_TYPE"}]}
+						},
+						{
+							"@type": "com.amazon.alexa.behaviors.model.OpaquePayloadOperationNode",
+							"type": "Alexa.TextCommand",
+							"skillId": "amzn1.ask.1p.tellalexa",
+							"operationPayload": {"deviceSerialNumber": "`+office.SerialNumber+`", "deviceType": "`+office.DeviceType+`", "text": "turn off the bedroom lamp"}
+						}
+					]
+				}
+			]
+		}
+	}`),
+		json.RawMessage(`{"type": "CustomUtterance", "skillId": "amzn1.ask.1p.customutterance", "payload": {"utterance": "good night", "locale": "en-US"}}`),
+		json.RawMessage(`{"type": "AlarmTrigger", "payload": {"schedule": {"triggerTime": "223000", "recurrence": "FREQ=DAILY;INTERVAL=1", "timeZoneId": "America/Los_Angeles"}}}`),
+	)
+
+	now := time.Now()
+	s.AddHistory(HistoryRecord{
+		Timestamp: now.Add(-10 * time.Minute),
+		Device:    &kitchen,
+		Utterance: "what's the weather",
+		Response:  "Right now it's 68 degrees and sunny.",
+	})
+	s.AddHistory(HistoryRecord{
+		Timestamp: now.Add(-5 * time.Minute),
+		Device:    &office,
+		Utterance: "set a timer for ten minutes",
+		Response:  "Ten minutes, starting now.",
+	})
+
+	s.Answer("what time is it", "It's "+now.Format("3:04 PM")+".")
+	s.Answer("what's the weather", "Right now it's 68 degrees and sunny.")
+
+	for _, d := range []string{"Kitchen Echo", "Living Room Echo Show", "Office"} {
+		s.AddConversation(d)
+	}
+
+	return s
+}
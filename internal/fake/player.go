@@ -0,0 +1,155 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Track is an item in a device's fake play queue
+type Track struct {
+	Title    string
+	Artist   string
+	Album    string
+	Provider string
+	Length   time.Duration
+}
+
+// PlayerState is the media state of a device
+type PlayerState struct {
+	State    string // PLAYING, PAUSED or IDLE
+	Queue    []Track
+	Current  int // index into Queue
+	Position time.Duration
+	Shuffle  bool
+	Repeat   bool
+}
+
+// providerNames are the display names of the music providers the fake knows
+var providerNames = map[string]string{
+	"AMAZON_MUSIC":  "Amazon Music",
+	"SPOTIFY":       "Spotify",
+	"TUNEIN":        "TuneIn",
+	"APPLE_MUSIC":   "Apple Music",
+	"DEEZER":        "Deezer",
+	"I_HEART_RADIO": "iHeartRadio",
+	"CLOUDPLAYER":   "My Library",
+}
+
+// searchResult is the track a music search for phrase finds
+func searchResult(phrase, providerID string) Track {
+	provider, ok := providerNames[providerID]
+	if !ok {
+		provider = providerID
+	}
+	return Track{
+		Title:    "Best of " + phrase,
+		Artist:   "Fake Radio",
+		Provider: provider,
+		Length:   3 * time.Minute,
+	}
+}
+
+// Play starts playing a queue of tracks on the device with a serial
+func (s *Server) Play(serial string, tracks ...Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players[serial] = &PlayerState{State: "PLAYING", Queue: tracks}
+}
+
+// Player returns the media state of the device with a serial
+func (s *Server) Player(serial string) PlayerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.players[serial]; ok {
+		return *p
+	}
+	return PlayerState{State: "IDLE"}
+}
+
+func (s *Server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	serial := r.URL.Query().Get("deviceSerialNumber")
+	p, ok := s.players[serial]
+	if !ok || len(p.Queue) == 0 {
+		writeJSON(w, map[string]interface{}{"playerInfo": map[string]interface{}{"state": nil}})
+		return
+	}
+
+	selected := func(on bool) string {
+		if on {
+			return "SELECTED"
+		}
+		return "ENABLED"
+	}
+
+	track := p.Queue[p.Current]
+	writeJSON(w, map[string]interface{}{
+		"playerInfo": map[string]interface{}{
+			"state": p.State,
+			"infoText": map[string]string{
+				"title":    track.Title,
+				"subText1": track.Artist,
+				"subText2": track.Album,
+			},
+			"provider": map[string]string{"providerName": track.Provider},
+			"progress": map[string]int64{
+				"mediaLength":   int64(track.Length.Seconds()),
+				"mediaProgress": int64(p.Position.Seconds()),
+			},
+			"volume": map[string]interface{}{"volume": s.volumes[serial], "muted": false},
+			"transport": map[string]string{
+				"shuffle": selected(p.Shuffle),
+				"repeat":  selected(p.Repeat),
+			},
+		},
+	})
+}
+
+func (s *Server) handlePlayerCommand(w http.ResponseWriter, r *http.Request) {
+	var cmd struct {
+		Type          string `json:"type"`
+		MediaPosition int64  `json:"mediaPosition"` // milliseconds
+		Shuffle       bool   `json:"shuffle"`
+		Repeat        bool   `json:"repeat"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		http.Error(w, `{"message":"malformed request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.players[r.URL.Query().Get("deviceSerialNumber")]
+	if !ok || len(p.Queue) == 0 {
+		http.Error(w, `{"message":"nothing is playing"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch cmd.Type {
+	case "PlayCommand":
+		p.State = "PLAYING"
+	case "PauseCommand":
+		p.State = "PAUSED"
+	case "NextCommand":
+		p.Current = (p.Current + 1) % len(p.Queue)
+		p.Position = 0
+	case "PreviousCommand":
+		p.Current = (p.Current + len(p.Queue) - 1) % len(p.Queue)
+		p.Position = 0
+	case "SeekCommand":
+		p.Position = min(time.Duration(cmd.MediaPosition)*time.Millisecond, p.Queue[p.Current].Length)
+	case "ShuffleCommand":
+		p.Shuffle = cmd.Shuffle
+	case "RepeatCommand":
+		p.Repeat = cmd.Repeat
+	default:
+		http.Error(w, `{"message":"unknown command"}`, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,810 @@
+// Package fake implements an in-memory stand-in for the Amazon endpoints used by
+// api.Client, so the CLI can be exercised without an Amazon account.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/buddyh/alexa-cli/internal/api"
+)
+
+// CustomerID is the account ID the fake reports for every device
+const CustomerID = "AFAKECUSTOMER"
+
+// Routine is a routine as stored by the fake
+type Routine struct {
+	AutomationID string
+	Name         string
+	Sequence     json.RawMessage
+	Triggers     []json.RawMessage
+	Status       string                     // ENABLED or DISABLED
+	Extra        map[string]json.RawMessage // other automation fields, kept as sent
+}
+
+// Sequence is a sequence received on /api/behaviors/preview
+type Sequence struct {
+	BehaviorID string
+	Raw        json.RawMessage // the decoded sequenceJson
+	Operations []Operation     // every operation node, in document order
+}
+
+// Operation is a single operation node from a sequence
+type Operation struct {
+	Type    string
+	Payload map[string]interface{}
+}
+
+// HistoryRecord is a voice history entry served on customer-history-records-v2
+type HistoryRecord struct {
+	Timestamp time.Time
+	Device    *api.Device
+	Utterance string // what the user said
+	Response  string // what Alexa answered
+}
+
+// SmartHomeState is the last state set on a smart home device
+type SmartHomeState struct {
+	On         bool
+	Brightness int
+}
+
+// Responder produces Alexa's answer to a text command or Alexa+ message
+type Responder func(text string) string
+
+// Server is a fake Alexa backend. All hosts share a single listener, since
+// none of the paths the client uses collide.
+type Server struct {
+	srv *httptest.Server
+
+	mu             sync.Mutex
+	devices        []api.Device
+	volumes        map[string]int // serial -> speaker volume
+	players        map[string]*PlayerState
+	smartHome      []api.SmartHomeDevice
+	smartHomeState map[string]SmartHomeState
+	routines       []Routine
+	sequences      []Sequence
+	history        []HistoryRecord
+	conversations  []*conversation
+	answers        map[string]string
+	responder      Responder
+	failures       map[string][]int // path -> statuses to return before serving normally
+	sessionToken   string
+	csrf           string
+	activityCSRF   string
+	bearerToken    string
+	generation     int // bumped by ExpireSession so issued credentials change
+	idCounter      int
+	serialPrefix   string // differs between servers, so two fake accounts never share a device serial
+}
+
+// servers counts the fakes started by this process
+var servers atomic.Int64
+
+// NewServer starts an empty fake backend on a loopback port
+func NewServer() *Server {
+	s := &Server{
+		serialPrefix:   fmt.Sprintf("G%dFAKE", servers.Add(1)-1),
+		volumes:        make(map[string]int),
+		players:        make(map[string]*PlayerState),
+		smartHomeState: make(map[string]SmartHomeState),
+		answers:        make(map[string]string),
+		failures:       make(map[string][]int),
+		responder: func(text string) string {
+			return fmt.Sprintf("You said: %s", text)
+		},
+	}
+	s.rotateCredentials()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ap/exchangetoken/cookies", s.handleExchangeCookies)
+	mux.HandleFunc("POST /auth/token", s.handleAuthToken)
+	mux.HandleFunc("GET /api/language", s.handleLanguage)
+	mux.HandleFunc("GET /api/devices-v2/device", s.cookieAuth(s.handleDevices))
+	mux.HandleFunc("GET /api/devices/deviceType/dsn/audio/v1/allDeviceVolumes", s.cookieAuth(s.handleVolumes))
+	mux.HandleFunc("GET /api/np/player", s.cookieAuth(s.handlePlayer))
+	mux.HandleFunc("POST /api/np/command", s.cookieAuth(s.handlePlayerCommand))
+	mux.HandleFunc("POST /api/behaviors/preview", s.cookieAuth(s.handlePreview))
+	mux.HandleFunc("GET /api/behaviors/automations", s.cookieAuth(s.handleAutomations))
+	mux.HandleFunc("POST /api/behaviors/automations", s.cookieAuth(s.handleCreateAutomation))
+	mux.HandleFunc("PUT /api/behaviors/automations/{id}", s.cookieAuth(s.handleUpdateAutomation))
+	mux.HandleFunc("DELETE /api/behaviors/automations/{id}", s.cookieAuth(s.handleDeleteAutomation))
+	mux.HandleFunc("GET /api/behaviors/entities", s.cookieAuth(s.handleEntities))
+	mux.HandleFunc("GET /api/phoenix", s.cookieAuth(s.handlePhoenix))
+	mux.HandleFunc("PUT /api/phoenix/state", s.cookieAuth(s.handlePhoenixState))
+	mux.HandleFunc("GET /alexa-privacy/apd/activity", s.handleActivityPage)
+	mux.HandleFunc("POST /alexa-privacy/apd/rvh/customer-history-records-v2/", s.handleHistory)
+	mux.HandleFunc("POST /v20160207/events", s.bearerAuth(s.handleEvents))
+	mux.HandleFunc("GET /v1/conversations", s.bearerAuth(s.handleConversations))
+	mux.HandleFunc("GET /v1/conversations/{id}/fragments/synchronize", s.bearerAuth(s.handleFragments))
+
+	s.srv = httptest.NewServer(s.injectFailures(mux))
+	return s
+}
+
+// URL returns the base URL of the fake
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Endpoints returns client endpoints that send every request to the fake
+func (s *Server) Endpoints() api.Endpoints {
+	return api.Endpoints{
+		API:      s.srv.URL,
+		Alexa:    s.srv.URL,
+		Activity: s.srv.URL,
+		AVS:      s.srv.URL,
+		Auth:     s.srv.URL,
+	}
+}
+
+// Close shuts the fake down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AddDevice registers an Echo device and returns it
+func (s *Server) AddDevice(name, family string) api.Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idCounter++
+	d := api.Device{
+		AccountName:           name,
+		SerialNumber:          fmt.Sprintf("%s%04d", s.serialPrefix, s.idCounter),
+		DeviceType:            "A3FAKEECHO",
+		DeviceFamily:          family,
+		DeviceOwnerCustomerID: CustomerID,
+		Online:                true,
+	}
+	s.devices = append(s.devices, d)
+	s.volumes[d.SerialNumber] = 50
+	return d
+}
+
+// AddSmartHomeDevice registers a smart home appliance and returns it
+func (s *Server) AddSmartHomeDevice(name, applianceType string) api.SmartHomeDevice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idCounter++
+	d := api.SmartHomeDevice{
+		EntityID:    fmt.Sprintf("fake-entity-%04d", s.idCounter),
+		ApplianceID: fmt.Sprintf("fake-appliance-%04d", s.idCounter),
+		Name:        name,
+		Description: "Fake " + strings.ToLower(applianceType),
+		Type:        applianceType,
+		Reachable:   true,
+	}
+	s.smartHome = append(s.smartHome, d)
+	return d
+}
+
+// AddRoutine registers a routine with the given sequence and trigger JSON and returns its automation ID
+func (s *Server) AddRoutine(name string, sequence json.RawMessage, triggers ...json.RawMessage) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idCounter++
+	id := fmt.Sprintf("amzn1.alexa.automation.fake-%04d", s.idCounter)
+	s.routines = append(s.routines, Routine{AutomationID: id, Name: name, Sequence: sequence, Triggers: triggers, Status: "ENABLED"})
+	return id
+}
+
+// AddConversation starts an Alexa+ conversation originating from a device and returns its ID
+func (s *Server) AddConversation(deviceName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newConversation(deviceName).id
+}
+
+// AddHistory appends a voice history record
+func (s *Server) AddHistory(r HistoryRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, r)
+}
+
+// Answer sets a canned answer for a question, used before the Responder
+func (s *Server) Answer(question, answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answers[strings.ToLower(question)] = answer
+}
+
+// SetResponder replaces the function answering questions without a canned answer
+func (s *Server) SetResponder(r Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = r
+}
+
+// FailNext makes the next requests to path fail with the given statuses, in order
+func (s *Server) FailNext(path string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], statuses...)
+}
+
+// ExpireSession invalidates every cookie, CSRF and bearer token issued so far,
+// as if Amazon had expired the session
+func (s *Server) ExpireSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotateCredentials()
+}
+
+// Sequences returns the sequences received so far
+func (s *Server) Sequences() []Sequence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Sequence(nil), s.sequences...)
+}
+
+// Volume returns the speaker volume of the device with a serial
+func (s *Server) Volume(serial string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.volumes[serial]
+}
+
+// SmartHomeState returns the last state set on a smart home entity
+func (s *Server) SmartHomeState(entityID string) SmartHomeState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.smartHomeState[entityID]
+}
+
+// rotateCredentials issues fresh credentials. The caller must hold s.mu.
+func (s *Server) rotateCredentials() {
+	s.generation++
+	s.sessionToken = fmt.Sprintf("fake-session-%d", s.generation)
+	s.csrf = fmt.Sprintf("fake-csrf-%d", s.generation)
+	s.activityCSRF = fmt.Sprintf("fake-activity-csrf-%d", s.generation)
+	s.bearerToken = fmt.Sprintf("Atna|fake-bearer-%d", s.generation)
+}
+
+// answer returns Alexa's reply to text. The caller must hold s.mu.
+func (s *Server) answer(text string) string {
+	if a, ok := s.answers[strings.ToLower(strings.TrimSpace(text))]; ok {
+		return a
+	}
+	return s.responder(text)
+}
+
+// injectFailures serves statuses queued with FailNext before handing over to next
+func (s *Server) injectFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		queue := s.failures[r.URL.Path]
+		status := 0
+		if len(queue) > 0 {
+			status = queue[0]
+			s.failures[r.URL.Path] = queue[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasSession reports whether the request carries the current session cookie
+func (s *Server) hasSession(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Contains(r.Header.Get("Cookie"), "session-token="+s.sessionToken)
+}
+
+// cookieAuth rejects requests without the current session cookie and CSRF token
+func (s *Server) cookieAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		csrf := s.csrf
+		s.mu.Unlock()
+
+		if !s.hasSession(r) || r.Header.Get("csrf") != csrf {
+			http.Error(w, `{"message":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bearerAuth rejects requests without the current bearer token
+func (s *Server) bearerAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		token := s.bearerToken
+		s.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, `{"message":"Invalid token"}`, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleExchangeCookies(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("source_token") == "" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	token := s.sessionToken
+	s.mu.Unlock()
+
+	type cookie struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	domain := r.PostForm.Get("domain")
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"tokens": map[string]interface{}{
+				"cookies": map[string][]cookie{
+					domain: {
+						{Name: "session-id", Value: "000-0000000-0000000"},
+						{Name: "session-token", Value: token},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (s *Server) handleAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("source_token") == "" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	token := s.bearerToken
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (s *Server) handleLanguage(w http.ResponseWriter, r *http.Request) {
+	if !s.hasSession(r) {
+		http.Error(w, `{"message":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	csrf := s.csrf
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: "csrf", Value: csrf, Path: "/"})
+	writeJSON(w, map[string]string{"language": "en-US"})
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	devices := append([]api.Device{}, s.devices...)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{"devices": devices})
+}
+
+func (s *Server) handleAutomations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]map[string]interface{}, len(s.routines))
+	for i, rt := range s.routines {
+		triggers := rt.Triggers
+		if triggers == nil {
+			triggers = []json.RawMessage{}
+		}
+		a := map[string]interface{}{}
+		for k, v := range rt.Extra {
+			a[k] = v
+		}
+		a["automationId"] = rt.AutomationID
+		a["name"] = rt.Name
+		a["sequence"] = rt.Sequence
+		a["triggers"] = triggers
+		a["status"] = rt.Status
+		out[i] = a
+	}
+	writeJSON(w, out)
+}
+
+// Routines returns the routines the fake holds
+func (s *Server) Routines() []Routine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Routine(nil), s.routines...)
+}
+
+// decodeRoutine reads a routine from a create or update request
+func decodeRoutine(r *http.Request) (Routine, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return Routine{}, err
+	}
+
+	var body struct {
+		Name     string            `json:"name"`
+		Sequence json.RawMessage   `json:"sequence"`
+		Triggers []json.RawMessage `json:"triggers"`
+		Status   string            `json:"status"`
+	}
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return Routine{}, err
+	}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return Routine{}, err
+	}
+	for _, k := range []string{"automationId", "name", "sequence", "triggers", "status"} {
+		delete(extra, k)
+	}
+
+	if body.Status == "" {
+		body.Status = "ENABLED"
+	}
+	return Routine{Name: body.Name, Sequence: body.Sequence, Triggers: body.Triggers, Status: body.Status, Extra: extra}, nil
+}
+
+func (s *Server) handleCreateAutomation(w http.ResponseWriter, r *http.Request) {
+	rt, err := decodeRoutine(r)
+	if err != nil {
+		http.Error(w, `{"message":"malformed request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idCounter++
+	rt.AutomationID = fmt.Sprintf("amzn1.alexa.automation.fake-%04d", s.idCounter)
+	s.routines = append(s.routines, rt)
+	writeJSON(w, map[string]string{"automationId": rt.AutomationID})
+}
+
+func (s *Server) handleUpdateAutomation(w http.ResponseWriter, r *http.Request) {
+	rt, err := decodeRoutine(r)
+	if err != nil {
+		http.Error(w, `{"message":"malformed request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.routines {
+		if s.routines[i].AutomationID == r.PathValue("id") {
+			rt.AutomationID = s.routines[i].AutomationID
+			s.routines[i] = rt
+			writeJSON(w, map[string]string{"automationId": rt.AutomationID})
+			return
+		}
+	}
+	http.Error(w, `{"message":"automation not found"}`, http.StatusNotFound)
+}
+
+func (s *Server) handleDeleteAutomation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.routines {
+		if s.routines[i].AutomationID == r.PathValue("id") {
+			s.routines = append(s.routines[:i], s.routines[i+1:]...)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	http.Error(w, `{"message":"automation not found"}`, http.StatusNotFound)
+}
+
+// sounds is the fake's sound library
+var sounds = []map[string]string{
+	{"id": "amzn_sfx_doorbell_chime_01", "displayName": "Doorbell Chime"},
+	{"id": "amzn_sfx_church_bell_1x_02", "displayName": "Church Bell"},
+	{"id": "amzn_sfx_cat_meow_1x_01", "displayName": "Cat Meow"},
+	{"id": "amzn_sfx_large_crowd_cheer_01", "displayName": "Crowd Cheer"},
+}
+
+func (s *Server) handleEntities(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("skillId") != "amzn1.ask.1p.sound" {
+		writeJSON(w, []interface{}{})
+		return
+	}
+	writeJSON(w, sounds)
+}
+
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		BehaviorID   string          `json:"behaviorId"`
+		SequenceJSON json.RawMessage `json:"sequenceJson"`
+		Status       string          `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"message":"malformed request"}`, http.StatusBadRequest)
+		return
+	}
+
+	// sequenceJson is normally a JSON document encoded as a string, but routines
+	// are replayed with the sequence object as-is
+	raw := payload.SequenceJSON
+	var encoded string
+	if json.Unmarshal(raw, &encoded) == nil {
+		raw = json.RawMessage(encoded)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		http.Error(w, `{"message":"sequenceJson is not valid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	seq := Sequence{BehaviorID: payload.BehaviorID, Raw: raw}
+	collectOperations(doc, &seq.Operations)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequences = append(s.sequences, seq)
+
+	for _, op := range seq.Operations {
+		serial, _ := op.Payload["deviceSerialNumber"].(string)
+
+		// Volume changes apply in order, so a temporary change ends up restored
+		if op.Type == "Alexa.DeviceControls.Volume" {
+			if v, ok := op.Payload["value"].(float64); ok {
+				if _, known := s.volumes[serial]; known {
+					s.volumes[serial] = int(v)
+				}
+			}
+			continue
+		}
+
+		// A music search plays a single made-up track from the phrase
+		if op.Type == "Alexa.Music.PlaySearchPhrase" {
+			phrase, _ := op.Payload["searchPhrase"].(string)
+			providerID, _ := op.Payload["musicProviderId"].(string)
+			if _, known := s.volumes[serial]; known {
+				s.players[serial] = &PlayerState{State: "PLAYING", Queue: []Track{searchResult(phrase, providerID)}}
+			}
+			continue
+		}
+
+		// Stop pauses the listed players, or every player for the household placeholder
+		if op.Type == "Alexa.DeviceControls.Stop" {
+			devices, _ := op.Payload["devices"].([]interface{})
+			for _, d := range devices {
+				dsn, _ := d.(map[string]interface{})["deviceSerialNumber"].(string)
+				for target, p := range s.players {
+					if dsn == "ALEXA_ALL_DSN" || dsn == target {
+						if p.State == "PLAYING" {
+							p.State = "PAUSED"
+						}
+					}
+				}
+			}
+			continue
+		}
+
+		// A text command is answered like a spoken question, so it shows up in voice history
+		if op.Type != "Alexa.TextCommand" {
+			continue
+		}
+		text, _ := op.Payload["text"].(string)
+		s.history = append(s.history, HistoryRecord{
+			Timestamp: time.Now(),
+			Device:    s.deviceBySerial(serial),
+			Utterance: text,
+			Response:  s.answer(text),
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// collectOperations appends every node with an operation type under v, in document order
+func collectOperations(v interface{}, ops *[]Operation) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if typ, ok := node["type"].(string); ok {
+			if payload, ok := node["operationPayload"].(map[string]interface{}); ok {
+				*ops = append(*ops, Operation{Type: typ, Payload: payload})
+			}
+		}
+		keys := make([]string, 0, len(node))
+		for k := range node {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k != "operationPayload" {
+				collectOperations(node[k], ops)
+			}
+		}
+	case []interface{}:
+		for _, item := range node {
+			collectOperations(item, ops)
+		}
+	}
+}
+
+// deviceBySerial returns the device with a serial, or nil. The caller must hold s.mu.
+func (s *Server) deviceBySerial(serial string) *api.Device {
+	for i := range s.devices {
+		if s.devices[i].SerialNumber == serial {
+			d := s.devices[i]
+			return &d
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleVolumes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	volumes := make([]api.DeviceVolume, len(s.devices))
+	for i, d := range s.devices {
+		volumes[i] = api.DeviceVolume{
+			SerialNumber:  d.SerialNumber,
+			DeviceType:    d.DeviceType,
+			SpeakerVolume: s.volumes[d.SerialNumber],
+		}
+	}
+	writeJSON(w, map[string]interface{}{"volumes": volumes})
+}
+
+func (s *Server) handlePhoenix(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	details := make(map[string]api.SmartHomeDevice, len(s.smartHome))
+	for _, d := range s.smartHome {
+		details[d.ApplianceID] = d
+	}
+	writeJSON(w, map[string]interface{}{
+		"networkDetail": []interface{}{
+			map[string]interface{}{"applianceDetails": details},
+		},
+	})
+}
+
+func (s *Server) handlePhoenixState(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ControlRequests []struct {
+			EntityID   string                 `json:"entityId"`
+			Parameters map[string]interface{} `json:"parameters"`
+		} `json:"controlRequests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"message":"malformed request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var responses, errs []map[string]interface{}
+	for _, req := range payload.ControlRequests {
+		known := false
+		for _, d := range s.smartHome {
+			known = known || d.EntityID == req.EntityID
+		}
+		if !known {
+			errs = append(errs, map[string]interface{}{"entity": map[string]string{"entityId": req.EntityID}, "code": "ENDPOINT_UNREACHABLE"})
+			continue
+		}
+
+		state := s.smartHomeState[req.EntityID]
+		switch req.Parameters["action"] {
+		case "turnOn":
+			state.On = true
+		case "turnOff":
+			state.On = false
+		case "setBrightness":
+			if b, ok := req.Parameters["brightness"].(float64); ok {
+				state.Brightness = int(b)
+				state.On = b > 0
+			}
+		}
+		s.smartHomeState[req.EntityID] = state
+		responses = append(responses, map[string]interface{}{"entityId": req.EntityID, "code": "SUCCESS"})
+	}
+
+	writeJSON(w, map[string]interface{}{"controlResponses": responses, "errors": errs})
+}
+
+func (s *Server) handleActivityPage(w http.ResponseWriter, r *http.Request) {
+	if !s.hasSession(r) {
+		http.Redirect(w, r, "/ap/signin", http.StatusFound)
+		return
+	}
+
+	s.mu.Lock()
+	token := s.activityCSRF
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><head><meta name="csrf-token" content="%s"></head><body></body></html>`, token)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.Contains(r.Header.Get("Cookie"), "session-token="+s.sessionToken) ||
+		r.Header.Get("anti-csrftoken-a2z") != s.activityCSRF {
+		http.Error(w, `{"message":"Forbidden"}`, http.StatusForbidden)
+		return
+	}
+
+	start, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+	end, _ := strconv.ParseInt(r.URL.Query().Get("endTime"), 10, 64)
+
+	type item struct {
+		RecordItemType string `json:"recordItemType"`
+		TranscriptText string `json:"transcriptText"`
+	}
+	type record struct {
+		RecordKey               string `json:"recordKey"`
+		Timestamp               int64  `json:"timestamp"`
+		VoiceHistoryRecordItems []item `json:"voiceHistoryRecordItems"`
+	}
+
+	// Newest first, like the real endpoint
+	records := []record{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		h := s.history[i]
+		ts := h.Timestamp.UnixMilli()
+		if ts < start || (end > 0 && ts > end) {
+			continue
+		}
+
+		deviceType, serial := "", ""
+		if h.Device != nil {
+			deviceType, serial = h.Device.DeviceType, h.Device.SerialNumber
+		}
+		rec := record{
+			RecordKey: fmt.Sprintf("%s#%d#%s#%s", CustomerID, ts, deviceType, serial),
+			Timestamp: ts,
+		}
+		if h.Utterance != "" {
+			rec.VoiceHistoryRecordItems = append(rec.VoiceHistoryRecordItems, item{"ASR_REPLACEMENT_TEXT", h.Utterance})
+		}
+		if h.Response != "" {
+			rec.VoiceHistoryRecordItems = append(rec.VoiceHistoryRecordItems, item{"TTS_REPLACEMENT_TEXT", h.Response})
+		}
+		records = append(records, rec)
+	}
+
+	writeJSON(w, map[string]interface{}{"customerHistoryRecords": records})
+}